@@ -0,0 +1,83 @@
+// Package breach tells whether a password appeared in a known data breach.
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Checker reports whether password is known from a data breach
+type Checker interface {
+	Breached(password string) (bool, error)
+}
+
+// Length of the SHA-1 prefix naming each range file
+const PrefixLength = 5
+
+// RangeStore looks passwords up in a local copy of a breached-password corpus
+// split into k-anonymity range files, the layout of the Have I Been Pwned
+// range API and its downloader. The file <dir>/<first 5 hex digits of the
+// SHA-1>.txt lists the remaining 35 digits of every hash with that prefix, one
+// "SUFFIX:COUNT" line each. Only the one file for the password's prefix is
+// read, so the corpus never has to fit in memory.
+type RangeStore struct {
+	dir      string
+	minCount int
+}
+
+// NewRangeStore opens the corpus in dir. Passwords seen fewer than minCount
+// times are not reported; the padding lines some downloads contain have a
+// count of 0 and are never reported.
+func NewRangeStore(dir string, minCount int) (*RangeStore, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if minCount < 1 {
+		minCount = 1
+	}
+	return &RangeStore{dir: dir, minCount: minCount}, nil
+}
+
+func (s *RangeStore) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:PrefixLength], hash[PrefixLength:]
+
+	file, err := os.Open(filepath.Join(s.dir, prefix+".txt"))
+	if err != nil {
+		// A partial corpus simply has no hashes with this prefix
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		entry, count, found := strings.Cut(line, ":")
+		if !strings.EqualFold(entry, suffix) {
+			continue
+		}
+		n := 1
+		if found {
+			if n, err = strconv.Atoi(count); err != nil {
+				return false, fmt.Errorf("malformed line in %s.txt", prefix)
+			}
+		}
+		return n >= s.minCount, nil
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,133 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hashParts splits the SHA-1 of password into its range file prefix and suffix
+func hashParts(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:PrefixLength], hash[PrefixLength:]
+}
+
+// writeRange writes the range file holding password's hash with lines, where
+// "%s" stands for the password's suffix and "%l" for it in lower case
+func writeRange(t *testing.T, dir, password string, lines ...string) {
+	t.Helper()
+	prefix, suffix := hashParts(password)
+	var content strings.Builder
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, "%s", suffix)
+		line = strings.ReplaceAll(line, "%l", strings.ToLower(suffix))
+		content.WriteString(line + "\r\n")
+	}
+	if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(content.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRangeStoreBreached(t *testing.T) {
+	other := strings.Repeat("0", 40-PrefixLength)
+
+	tests := []struct {
+		name     string
+		lines    []string // Range file for the password, nil for none
+		minCount int
+		want     bool
+		wantErr  bool
+	}{
+		{"listed", []string{other + ":3", "%s:42"}, 1, true, false},
+		{"listed in lower case", []string{"%l:42"}, 1, true, false},
+		{"not in its range", []string{other + ":42"}, 1, false, false},
+		{"no range file", nil, 1, false, false},
+		{"empty range file", []string{}, 1, false, false},
+		{"at the minimum count", []string{"%s:5"}, 5, true, false},
+		{"below the minimum count", []string{"%s:4"}, 5, false, false},
+		{"padding line", []string{"%s:0"}, 1, false, false},
+		{"line without count", []string{"%s"}, 1, true, false},
+		{"line without count below the minimum", []string{"%s"}, 2, false, false},
+		{"malformed count", []string{"%s:many"}, 1, false, true},
+		{"malformed line of another hash", []string{other + ":many", "%s:1"}, 1, true, false},
+		{"blank lines", []string{"", "%s:7", ""}, 1, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			const password = "correct horse battery staple"
+			if tt.lines != nil {
+				writeRange(t, dir, password, tt.lines...)
+			}
+
+			store, err := NewRangeStore(dir, tt.minCount)
+			if err != nil {
+				t.Fatalf("NewRangeStore: %v", err)
+			}
+			got, err := store.Breached(password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Breached = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeStoreReadsOnlyThePasswordsRange(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "password1", "%s:100")
+
+	store, err := NewRangeStore(dir, 1)
+	if err != nil {
+		t.Fatalf("NewRangeStore: %v", err)
+	}
+
+	for password, want := range map[string]bool{"password1": true, "password2": false, "": false} {
+		got, err := store.Breached(password)
+		if err != nil {
+			t.Fatalf("Breached(%q): %v", password, err)
+		}
+		if got != want {
+			t.Errorf("Breached(%q) = %v, want %v", password, got, want)
+		}
+	}
+}
+
+func TestNewRangeStore(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "corpus.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		dir          string
+		minCount     int
+		wantMinCount int
+		wantErr      bool
+	}{
+		{"directory", dir, 3, 3, false},
+		{"minimum count raised to 1", dir, 0, 1, false},
+		{"negative minimum count", dir, -5, 1, false},
+		{"missing directory", filepath.Join(dir, "missing"), 1, 0, true},
+		{"file instead of directory", file, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewRangeStore(tt.dir, tt.minCount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && store.minCount != tt.wantMinCount {
+				t.Errorf("minCount = %d, want %d", store.minCount, tt.wantMinCount)
+			}
+		})
+	}
+}
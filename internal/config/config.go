@@ -1,14 +1,126 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
+// Largest ARGON2_MEMORY_KIB accepted, 4 GiB per hash
+const maxArgon2MemoryKiB = 4 << 20
+
 type Config struct {
 	DatabasePath string
 	JWTSecret    []byte
 	ServerPort   string
 	AppName      string
+
+	// Point expiry
+	PointExpiryDays  int // Lifetime of newly granted points, 0 disables expiry
+	ExpiringSoonDays int // Default look-ahead window for the expiring points endpoint
+	ExpiryJobHour    int // Local hour at which the nightly expiry job runs
+
+	// Campaigns
+	AdminEmails          []string // Users promoted to admin on startup
+	SignupBonus          uint     // Reward of the default welcome campaign seeded on first start
+	CampaignInitialFunds uint     // Points seeded into the campaign account on first start
+	BirthdayJobHour      int      // Local hour at which birthday bonuses are paid
+
+	// Merchants
+	PaymentIntentTTLMinutes int // How long customers have to confirm a payment intent
+
+	// QR codes
+	QRSecret     []byte // Key signing dynamic QR payloads, defaults to the JWT secret
+	QRTTLMinutes int    // Default lifetime of dynamic QR codes
+
+	// Currency conversion
+	ConversionQuoteTTLSeconds int // How long a conversion quote locks its rate
+
+	// Escrow
+	EscrowTimeoutHours    int // Default time before held escrows are refunded
+	EscrowMaxHours        int // Longest hold a sender may ask for
+	EscrowJobIntervalMins int // How often expired escrows are refunded
+
+	// Payment requests
+	PaymentReminderHours int // Minimum time between reminders for an unpaid share
+	ReminderJobHour      int // Hour of day unpaid shares are reminded
+
+	// Vouchers
+	VoucherValidityDays int // Default lifetime of a voucher before it is refunded
+	VoucherMaxDays      int // Longest lifetime a voucher may be given
+
+	// Transaction PIN
+	TransferPINThreshold uint // Transfers of at least this many points need the PIN, 0 disables the check
+	PINMaxAttempts       int  // Wrong PINs in a row before the PIN is locked
+	PINLockoutMinutes    int  // How long a locked PIN stays locked
+
+	// Two-factor authentication
+	MFAIssuer              string // Account issuer shown in authenticator apps
+	MFAChallengeTTLMinutes int    // How long a login may wait for its second factor
+	MFAMaxAttempts         int    // Wrong codes in a row before 2FA is locked
+	MFALockoutMinutes      int    // How long locked 2FA stays locked
+
+	// Login throttling
+	LoginDelayAfter     int // Failed logins after which attempts are delayed
+	LoginMaxFailures    int // Failed logins for one email before it is locked
+	LoginIPMaxFailures  int // Failed logins from one IP before it is locked
+	LoginLockoutMinutes int // Lock duration, also how long failures are remembered
+
+	// Mail
+	MailDriver   string // smtp, or log to write mail to MailLogFile or the server log
+	MailFrom     string
+	MailLogFile  string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Password reset
+	PasswordResetURL        string // Page of the client app that reads the token query parameter
+	PasswordResetTTLMinutes int    // How long a reset link works
+
+	// Email verification
+	RequireVerifiedEmail           bool   // Unverified users cannot send points
+	EmailVerificationURL           string // Page of the client app that reads the token query parameter
+	EmailVerificationTTLHours      int    // How long a verification link works
+	EmailVerificationResendSeconds int    // Minimum time between verification emails to one user
+	EmailVerificationMaxPerDay     int    // Verification emails to one user per day
+
+	// Phone verification
+	DefaultCountryCode    string // Calling code for numbers given in national format, e.g. 66
+	PhoneOTPTTLMinutes    int    // How long a texted code works
+	PhoneOTPMaxAttempts   int    // Wrong entries before a code is used up
+	PhoneOTPResendSeconds int    // Minimum time between codes to one user
+	PhoneOTPMaxPerDay     int    // Codes to one user per day
+
+	// Encryption of personal data
+	PIIKeyProvider string // env to read the keys below, file to read PIIKeyFile, or dev for the public development keys
+	PIIKeys        string // Key-encryption keys as "id:base64key,id:base64key", required by the env provider
+	PIIActiveKey   string // ID of the key that seals new values, optional with a single key
+	PIIIndexKey    string // Base64 key of the blind indexes
+	PIIKeyFile     string // JSON key file for the file provider
+
+	// Password hashing
+	PasswordHashAlgorithm string // argon2id, or bcrypt
+	Argon2MemoryKiB       int    // Memory used per argon2id hash
+	Argon2Iterations      int    // Passes over that memory
+	Argon2Parallelism     int    // Threads per argon2id hash
+	BcryptCost            int    // Cost of new hashes when the algorithm is bcrypt
+	HashConcurrency       int    // Hashes computed at once, which bounds the memory argon2id takes
+
+	// Password policy
+	PasswordMinLength          int
+	PasswordMaxLength          int  // 0 for no limit
+	PasswordRequireLower       bool // Require a lowercase letter
+	PasswordRequireUpper       bool // Require an uppercase letter
+	PasswordRequireDigit       bool
+	PasswordRequireSymbol      bool
+	PasswordRejectPersonalInfo bool   // Reject passwords containing the user's email or name
+	BreachedPasswordsDir       string // Range files of a breached-password corpus, empty to skip the check
+	BreachedPasswordMinCount   int    // Times a password must have been seen in breaches to be rejected
 }
 
 func LoadConfig() *Config {
@@ -27,10 +139,161 @@ func LoadConfig() *Config {
 		serverPort = ":" + port
 	}
 
+	qrSecret := jwtSecret
+	if secret := os.Getenv("QR_SECRET"); secret != "" {
+		qrSecret = []byte(secret)
+	}
+
 	return &Config{
-		DatabasePath: databasePath,
-		JWTSecret:    jwtSecret,
-		ServerPort:   serverPort,
-		AppName:      "Fiber API Server v1.0.0",
+		DatabasePath:     databasePath,
+		JWTSecret:        jwtSecret,
+		ServerPort:       serverPort,
+		AppName:          "Fiber API Server v1.0.0",
+		PointExpiryDays:  getEnvInt("POINT_EXPIRY_DAYS", 365),
+		ExpiringSoonDays: getEnvInt("EXPIRING_SOON_DAYS", 30),
+		ExpiryJobHour:    getEnvInt("EXPIRY_JOB_HOUR", 2),
+
+		AdminEmails:          getEnvList("ADMIN_EMAILS"),
+		SignupBonus:          uint(getEnvInt("SIGNUP_BONUS", 1000)),
+		CampaignInitialFunds: uint(getEnvInt("CAMPAIGN_INITIAL_FUNDS", 1000000)),
+		BirthdayJobHour:      getEnvInt("BIRTHDAY_JOB_HOUR", 0),
+
+		PaymentIntentTTLMinutes: getEnvInt("PAYMENT_INTENT_TTL_MINUTES", 15),
+
+		QRSecret:     qrSecret,
+		QRTTLMinutes: getEnvInt("QR_TTL_MINUTES", 15),
+
+		ConversionQuoteTTLSeconds: getEnvInt("CONVERSION_QUOTE_TTL_SECONDS", 30),
+
+		EscrowTimeoutHours:    getEnvInt("ESCROW_TIMEOUT_HOURS", 72),
+		EscrowMaxHours:        getEnvInt("ESCROW_MAX_HOURS", 720),
+		EscrowJobIntervalMins: getEnvInt("ESCROW_JOB_INTERVAL_MINUTES", 5),
+
+		PaymentReminderHours: getEnvInt("PAYMENT_REMINDER_HOURS", 24),
+		ReminderJobHour:      getEnvInt("REMINDER_JOB_HOUR", 10),
+
+		VoucherValidityDays: getEnvInt("VOUCHER_VALIDITY_DAYS", 365),
+		VoucherMaxDays:      getEnvInt("VOUCHER_MAX_DAYS", 1825),
+
+		TransferPINThreshold: uint(getEnvInt("TRANSFER_PIN_THRESHOLD", 1000)),
+		PINMaxAttempts:       getEnvInt("PIN_MAX_ATTEMPTS", 5),
+		PINLockoutMinutes:    getEnvInt("PIN_LOCKOUT_MINUTES", 15),
+
+		MFAIssuer:              getEnvString("MFA_ISSUER", "Fiber API"),
+		MFAChallengeTTLMinutes: getEnvInt("MFA_CHALLENGE_TTL_MINUTES", 5),
+		MFAMaxAttempts:         getEnvInt("MFA_MAX_ATTEMPTS", 5),
+		MFALockoutMinutes:      getEnvInt("MFA_LOCKOUT_MINUTES", 15),
+
+		LoginDelayAfter:     getEnvInt("LOGIN_DELAY_AFTER", 3),
+		LoginMaxFailures:    getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LoginIPMaxFailures:  getEnvInt("LOGIN_IP_MAX_FAILURES", 50),
+		LoginLockoutMinutes: getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+
+		MailDriver:   getEnvString("MAIL_DRIVER", "log"),
+		MailFrom:     getEnvString("MAIL_FROM", "no-reply@example.com"),
+		MailLogFile:  os.Getenv("MAIL_LOG_FILE"),
+		SMTPHost:     getEnvString("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+
+		PasswordResetURL:        getEnvString("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		PasswordResetTTLMinutes: getEnvInt("PASSWORD_RESET_TTL_MINUTES", 30),
+
+		RequireVerifiedEmail:           getEnvBool("REQUIRE_VERIFIED_EMAIL", true),
+		EmailVerificationURL:           getEnvString("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email"),
+		EmailVerificationTTLHours:      getEnvInt("EMAIL_VERIFICATION_TTL_HOURS", 48),
+		EmailVerificationResendSeconds: getEnvInt("EMAIL_VERIFICATION_RESEND_SECONDS", 60),
+		EmailVerificationMaxPerDay:     getEnvInt("EMAIL_VERIFICATION_MAX_PER_DAY", 5),
+
+		DefaultCountryCode:    getEnvString("DEFAULT_COUNTRY_CODE", "66"),
+		PhoneOTPTTLMinutes:    getEnvInt("PHONE_OTP_TTL_MINUTES", 10),
+		PhoneOTPMaxAttempts:   getEnvInt("PHONE_OTP_MAX_ATTEMPTS", 5),
+		PhoneOTPResendSeconds: getEnvInt("PHONE_OTP_RESEND_SECONDS", 60),
+		PhoneOTPMaxPerDay:     getEnvInt("PHONE_OTP_MAX_PER_DAY", 5),
+
+		PIIKeyProvider: getEnvString("PII_KEY_PROVIDER", "env"),
+		PIIKeys:        os.Getenv("PII_KEYS"),
+		PIIActiveKey:   os.Getenv("PII_ACTIVE_KEY"),
+		PIIIndexKey:    os.Getenv("PII_INDEX_KEY"),
+		PIIKeyFile:     os.Getenv("PII_KEY_FILE"),
+
+		PasswordHashAlgorithm: getEnvString("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		Argon2MemoryKiB:       getEnvInt("ARGON2_MEMORY_KIB", 19456),
+		Argon2Iterations:      getEnvInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism:     getEnvInt("ARGON2_PARALLELISM", 1),
+		BcryptCost:            getEnvInt("BCRYPT_COST", 12),
+		HashConcurrency:       getEnvInt("PASSWORD_HASH_CONCURRENCY", 4),
+
+		PasswordMinLength:          getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:          getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireLower:       getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireUpper:       getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireDigit:       getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:      getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordRejectPersonalInfo: getEnvBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+		BreachedPasswordsDir:       os.Getenv("BREACHED_PASSWORDS_DIR"),
+		BreachedPasswordMinCount:   getEnvInt("BREACHED_PASSWORD_MIN_COUNT", 1),
+	}
+}
+
+// ValidatePasswordHashing checks the password hashing settings fit the types
+// they are converted to and are in range, so that a typo fails at startup
+// rather than wrapping around into a setting argon2 cannot run with
+func (c *Config) ValidatePasswordHashing() error {
+	if c.Argon2Parallelism < 1 || c.Argon2Parallelism > math.MaxUint8 {
+		return fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d", math.MaxUint8)
+	}
+	if c.Argon2MemoryKiB < 8*c.Argon2Parallelism || c.Argon2MemoryKiB > maxArgon2MemoryKiB {
+		return fmt.Errorf("ARGON2_MEMORY_KIB must be between 8 × ARGON2_PARALLELISM and %d", maxArgon2MemoryKiB)
+	}
+	if c.Argon2Iterations < 1 || int64(c.Argon2Iterations) > math.MaxUint32 {
+		return fmt.Errorf("ARGON2_ITERATIONS must be between 1 and %d", uint32(math.MaxUint32))
+	}
+	if c.BcryptCost < 4 || c.BcryptCost > 31 {
+		return errors.New("BCRYPT_COST must be between 4 and 31")
+	}
+	if c.HashConcurrency < 1 {
+		return errors.New("PASSWORD_HASH_CONCURRENCY must be at least 1")
+	}
+	return nil
+}
+
+// getEnvInt reads an integer environment variable, falling back to def when unset or invalid
+func getEnvInt(key string, def int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// getEnvBool reads a boolean environment variable, falling back to def when unset or invalid
+func getEnvBool(key string, def bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// getEnvString reads a string environment variable, falling back to def when unset
+func getEnvString(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getEnvList reads a comma separated environment variable, ignoring empty items
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
 	"log"
 
 	"gorm.io/driver/sqlite"
@@ -12,21 +13,128 @@ type Database struct {
 	DB *gorm.DB
 }
 
-func NewDatabase(databasePath string) *Database {
+// NewDatabase opens and migrates the database. Columns tagged serializer:pii
+// are encrypted with cipher.
+func NewDatabase(databasePath string, cipher *pii.Cipher) *Database {
+	pii.Use(cipher)
+
 	db, err := gorm.Open(sqlite.Open(databasePath), &gorm.Config{})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Accounts created before email verification existed count as verified
+	backfillVerified := !db.Migrator().HasColumn(&models.User{}, "email_verified")
+
 	// Auto migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Transfer{})
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Transfer{},
+		&models.PointLot{},
+		&models.Currency{},
+		&models.Wallet{},
+		&models.ExchangeRate{},
+		&models.Conversion{},
+		&models.Escrow{},
+		&models.PaymentRequest{},
+		&models.PaymentRequestShare{},
+		&models.Notification{},
+		&models.Voucher{},
+		&models.RecoveryCode{},
+		&models.LoginThrottle{},
+		&models.SecurityEvent{},
+		&models.UserToken{},
+		&models.PhoneVerification{},
+		&models.ProfileChange{},
+		&models.Session{},
+		&models.UserAPIKey{},
+		&models.Campaign{},
+		&models.CampaignReward{},
+		&models.CampaignAccount{},
+		&models.CampaignFunding{},
+		&models.Referral{},
+		&models.CatalogItem{},
+		&models.RedemptionOrder{},
+		&models.Merchant{},
+		&models.MerchantAPIKey{},
+		&models.PaymentIntent{},
+	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// AutoMigrate does not relax NOT NULL on existing columns; system entries
+	// such as bonuses and expired points leave one side of a transfer empty
+	if err := relaxNotNull(db, &models.Transfer{}, "from_user_id", "FromUserID"); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+	if err := relaxNotNull(db, &models.Transfer{}, "to_user_id", "ToUserID"); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	// SQLite cannot add a UNIQUE column to an existing table, so uniqueness of
+	// columns added to users later is enforced with a separate index
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_referral_code ON users(referral_code)").Error; err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	// Encrypt personal data stored before encryption existed; uniqueness is
+	// enforced on the blind indexes once they are all filled in
+	if count, err := SealPII(db, cipher, false); err != nil {
+		log.Fatal("Failed to encrypt personal data:", err)
+	} else if count > 0 {
+		log.Printf("Encrypted personal data in %d rows", count)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users(email_index)").Error; err != nil {
+		log.Fatal("Failed to migrate database (are there emails differing only in case?):", err)
+	}
+
+	// Security events and login throttles keep blind indexes, not emails
+	if count, err := indexSecurityEmails(db, cipher); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	} else if count > 0 {
+		log.Printf("Replaced emails with blind indexes in %d security rows", count)
+	}
+
+	// Several accounts may claim a number, but only one can have it verified
+	if err := db.Exec("DROP INDEX IF EXISTS idx_users_verified_phone").Error; err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_phone_index ON users(phone_index) WHERE phone_verified = 1").Error; err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	if backfillVerified {
+		if err := db.Model(&models.User{}).Where("1 = 1").Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": gorm.Expr("created_at"),
+		}).Error; err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+
 	return &Database{DB: db}
 }
 
+// relaxNotNull drops the NOT NULL constraint from column when it is still present
+func relaxNotNull(db *gorm.DB, model interface{}, column, field string) error {
+	columnTypes, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+
+	for _, columnType := range columnTypes {
+		if columnType.Name() != column {
+			continue
+		}
+		if nullable, ok := columnType.Nullable(); ok && !nullable {
+			return db.Migrator().AlterColumn(model, field)
+		}
+	}
+
+	return nil
+}
+
 func (d *Database) GetDB() *gorm.DB {
 	return d.DB
 }
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"database/sql"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Rows read per batch while sealing
+const sealBatchSize = 500
+
+// blindIndex is a lookup column computed from an encrypted column
+type blindIndex struct {
+	source string
+	column string
+	value  func(c *pii.Cipher, plaintext string) string
+}
+
+// sealedTables lists every column tagged serializer:pii
+var sealedTables = []struct {
+	table   string
+	columns []string
+	indexes []blindIndex
+}{
+	{
+		table:   "users",
+		columns: []string{"email", "phone_number", "dob"},
+		indexes: []blindIndex{
+			{source: "email", column: "email_index", value: (*pii.Cipher).EmailIndex},
+			{source: "phone_number", column: "phone_index", value: (*pii.Cipher).PhoneIndex},
+		},
+	},
+	{table: "phone_verifications", columns: []string{"phone_number"}},
+	{table: "profile_changes", columns: []string{"old_value", "new_value"}},
+}
+
+// SealPII encrypts personal data still stored in plaintext, reseals values
+// from before they were bound to their column and fills in blind indexes. With rotate it also re-encrypts values sealed with any key
+// but the active one and recomputes every blind index, e.g. after the index
+// key changed. It returns the number of rows updated.
+func SealPII(db *gorm.DB, cipher *pii.Cipher, rotate bool) (int, error) {
+	updated := 0
+	for _, table := range sealedTables {
+		var plaintext []string
+		for _, column := range table.columns {
+			plaintext = append(plaintext, "("+column+" <> '' AND "+column+" NOT LIKE '"+pii.Prefix+"%')")
+		}
+
+		var lastID uint
+		for {
+			query := db.Table(table.table).
+				Select(append([]string{"id"}, table.columns...)).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(sealBatchSize)
+			if !rotate {
+				query = query.Where(strings.Join(plaintext, " OR "))
+			}
+
+			rows, err := query.Rows()
+			if err != nil {
+				return updated, err
+			}
+			var batch []sealRow
+			for rows.Next() {
+				row := sealRow{values: make([]sql.NullString, len(table.columns))}
+				dest := []interface{}{&row.id}
+				for i := range row.values {
+					dest = append(dest, &row.values[i])
+				}
+				if err := rows.Scan(dest...); err != nil {
+					rows.Close()
+					return updated, err
+				}
+				batch = append(batch, row)
+			}
+			rows.Close()
+			if len(batch) == 0 {
+				break
+			}
+
+			for _, row := range batch {
+				lastID = row.id
+
+				updates := map[string]interface{}{}
+				opened := map[string]string{}
+				for i, column := range table.columns {
+					value := row.values[i].String
+					plain, err := cipher.Open(value, pii.Column(table.table, column))
+					if err != nil {
+						return updated, err
+					}
+					opened[column] = plain
+					if value == "" || cipher.Current(value) || (!rotate && strings.HasPrefix(value, pii.Prefix)) {
+						continue
+					}
+					if updates[column], err = cipher.Seal(plain, pii.Column(table.table, column)); err != nil {
+						return updated, err
+					}
+				}
+				for _, index := range table.indexes {
+					if rotate || updates[index.source] != nil {
+						updates[index.column] = index.value(cipher, opened[index.source])
+					}
+				}
+				if len(updates) == 0 {
+					continue
+				}
+
+				if err := db.Table(table.table).Where("id = ?", row.id).Updates(updates).Error; err != nil {
+					return updated, err
+				}
+				updated++
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+// indexSecurityEmails replaces the plaintext emails that security events and
+// login throttles stored before they kept blind indexes instead, then drops
+// the old column. It returns the number of rows updated.
+func indexSecurityEmails(db *gorm.DB, cipher *pii.Cipher) (int, error) {
+	updated := 0
+
+	if db.Migrator().HasColumn(&models.SecurityEvent{}, "email") {
+		for {
+			var batch []struct {
+				ID    uint
+				Email string
+			}
+			if err := db.Table("security_events").Select("id", "email").
+				Where("email <> '' AND (email_index IS NULL OR email_index = '')").
+				Order("id").Limit(sealBatchSize).Scan(&batch).Error; err != nil {
+				return updated, err
+			}
+			if len(batch) == 0 {
+				break
+			}
+
+			for _, row := range batch {
+				if err := db.Table("security_events").Where("id = ?", row.ID).
+					Update("email_index", cipher.EmailIndex(row.Email)).Error; err != nil {
+					return updated, err
+				}
+				updated++
+			}
+		}
+
+		if err := db.Exec("DROP INDEX IF EXISTS idx_security_events_email").Error; err != nil {
+			return updated, err
+		}
+		if err := db.Migrator().DropColumn(&models.SecurityEvent{}, "email"); err != nil {
+			return updated, err
+		}
+		// SQLite drops a column by copying the table, which loses its indexes
+		if err := db.AutoMigrate(&models.SecurityEvent{}); err != nil {
+			return updated, err
+		}
+	}
+
+	// Blind indexes never contain "@"
+	var throttles []models.LoginThrottle
+	if err := db.Where("kind = ? AND key LIKE ?", "account", "%@%").Find(&throttles).Error; err != nil {
+		return updated, err
+	}
+	for _, throttle := range throttles {
+		if err := db.Model(&models.LoginThrottle{}).Where("id = ?", throttle.ID).
+			Update("key", cipher.EmailIndex(throttle.Key)).Error; err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+type sealRow struct {
+	id     uint
+	values []sql.NullString
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+var testKEK = bytes.Repeat([]byte{1}, pii.KeySize)
+
+func newTestDB(t *testing.T) (*gorm.DB, *pii.Cipher) {
+	t.Helper()
+	provider, err := pii.NewStaticKeyProvider("k1", map[string][]byte{"k1": testKEK}, bytes.Repeat([]byte{9}, pii.KeySize))
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider: %v", err)
+	}
+	c := pii.NewCipher(provider)
+	db := NewDatabase(filepath.Join(t.TempDir(), "test.db"), c).GetDB()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db, c
+}
+
+// sealV1 seals plaintext with key k1 the way values were sealed before they
+// were bound to their column
+func sealV1(t *testing.T, plaintext string) string {
+	t.Helper()
+	gcmSeal := func(key, plaintext []byte) []byte {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := make([]byte, aead.NonceSize())
+		rand.Read(nonce)
+		return aead.Seal(nonce, nonce, plaintext, []byte("k1"))
+	}
+	dek := bytes.Repeat([]byte{7}, pii.KeySize)
+	encode := base64.RawURLEncoding.EncodeToString
+	return "pii:v1:k1:" + encode(gcmSeal(testKEK, dek)) + ":" + encode(gcmSeal(dek, []byte(plaintext)))
+}
+
+func TestSealedValuesStayInTheirColumn(t *testing.T) {
+	db, c := newTestDB(t)
+
+	jane := models.User{Email: "jane@example.com", EmailIndex: c.EmailIndex("jane@example.com"), PhoneNumber: "+66812345678",
+		LBKCode: "LBK1", Password: "x", FirstName: "Jane", LastName: "Doe"}
+	john := models.User{Email: "john@example.com", EmailIndex: c.EmailIndex("john@example.com"),
+		LBKCode: "LBK2", Password: "x", FirstName: "John", LastName: "Doe"}
+	for _, user := range []*models.User{&jane, &john} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	var loaded models.User
+	if err := db.First(&loaded, jane.ID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if loaded.Email != "jane@example.com" || loaded.PhoneNumber != "+66812345678" {
+		t.Fatalf("loaded %q and %q", loaded.Email, loaded.PhoneNumber)
+	}
+
+	// Jane's sealed phone number copied into John's email
+	if err := db.Exec("UPDATE users SET email = (SELECT phone_number FROM users WHERE id = ?) WHERE id = ?", jane.ID, john.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&loaded, john.ID).Error; err == nil {
+		t.Errorf("a phone number copied into the email column opened as %q", loaded.Email)
+	}
+}
+
+func TestSealPIIUpgradesLegacyValues(t *testing.T) {
+	db, c := newTestDB(t)
+
+	user := models.User{Email: "jane@example.com", EmailIndex: c.EmailIndex("jane@example.com"),
+		LBKCode: "LBK1", Password: "x", FirstName: "Jane", LastName: "Doe"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	legacy := sealV1(t, "jane@example.com")
+	if err := db.Exec("UPDATE users SET email = ? WHERE id = ?", legacy, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var loaded models.User
+	if err := db.First(&loaded, user.ID).Error; err != nil || loaded.Email != "jane@example.com" {
+		t.Fatalf("legacy value loaded as %q, %v", loaded.Email, err)
+	}
+	count, err := SealPII(db, c, false)
+	if err != nil {
+		t.Fatalf("SealPII: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("SealPII updated %d rows, want 1", count)
+	}
+
+	var raw string
+	db.Table("users").Where("id = ?", user.ID).Select("email").Scan(&raw)
+	if !strings.HasPrefix(raw, pii.Prefix) || !c.Current(raw) {
+		t.Errorf("email stored as %q", raw)
+	}
+	if opened, err := c.Open(raw, pii.Column("users", "email")); err != nil || opened != "jane@example.com" {
+		t.Errorf("Open = %q, %v", opened, err)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+func NewAccountHandler(accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// Export account endpoint
+// @Summary Export Account Data
+// @Description Download everything stored about the authenticated user as a JSON file: profile, wallets, transfer history, orders, escrows, payment requests, vouchers, referrals, notifications, profile changes and security events
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.AccountExport
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/export [get]
+func (h *AccountHandler) ExportAccount(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	export, err := h.accountService.Export(userID)
+	if err != nil {
+		return accountError(c, err)
+	}
+
+	c.Attachment(fmt.Sprintf("account-export-%d.json", userID))
+	return c.JSON(export)
+}
+
+// Close account endpoint
+// @Summary Close Account
+// @Description Close the authenticated user's account. Any remaining balance must be swept to another LBK code. Personal data is erased and every session signed out; transfers are kept and the LBK code is never reused
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CloseAccountRequest true "Password, 2FA code and sweep target"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/close [post]
+func (h *AccountHandler) CloseAccount(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.CloseAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Password == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "password is required"})
+	}
+
+	user, err := h.accountService.Close(userID, req, c.IP())
+	if err != nil {
+		return accountError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+func accountError(c *fiber.Ctx, err error) error {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		return tooManyAttempts(c, throttled)
+	}
+
+	switch err.Error() {
+	case "balance must be zero or swept", "mfa code required", "cannot transfer points to yourself":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "invalid password", "invalid code":
+		return c.Status(401).JSON(models.ErrorResponse{Error: err.Error()})
+	case "user not found", "recipient user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "account already closed", "account has open escrows", "account has active vouchers", "account has open redemption orders":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	case "mfa locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
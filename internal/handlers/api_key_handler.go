@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// List API keys endpoint
+// @Summary List Personal API Keys
+// @Description The authenticated user's API keys, newest first, including revoked ones. Keys themselves are never shown again after creation
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UserAPIKeyListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.apiKeyService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Create API key endpoint
+// @Summary Create Personal API Key
+// @Description Issue an API key for the authenticated user's scripts and bots, sent in the X-API-Key header. Scopes are balance:read, history:read and transfer; keys with the transfer scope send at most transfer_limit points per 24 hours. The key is only returned once
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body models.CreateUserAPIKeyRequest true "Name, scopes and password"
+// @Success 201 {object} models.UserAPIKeyCreatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.CreateUserAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Name == "" || len(req.Scopes) == 0 || req.Password == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "name, scopes and password are required"})
+	}
+
+	response, err := h.apiKeyService.Create(userID, req, c.IP())
+	if err != nil {
+		return apiKeyError(c, err)
+	}
+
+	return c.Status(201).JSON(response)
+}
+
+// Revoke API key endpoint
+// @Summary Revoke Personal API Key
+// @Description Stop one of the authenticated user's API keys from working
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	keyID, err := c.ParamsInt("id")
+	if err != nil || keyID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid api key id"})
+	}
+
+	if err := h.apiKeyService.Revoke(userID, uint(keyID), c.IP()); err != nil {
+		return apiKeyError(c, err)
+	}
+
+	return c.SendStatus(204)
+}
+
+func apiKeyError(c *fiber.Ctx, err error) error {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		return tooManyAttempts(c, throttled)
+	}
+
+	switch msg := err.Error(); {
+	case msg == "invalid password", msg == "invalid code":
+		return c.Status(401).JSON(models.ErrorResponse{Error: msg})
+	case msg == "api key not found", msg == "user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: msg})
+	case strings.HasPrefix(msg, "at most "):
+		return c.Status(409).JSON(models.ErrorResponse{Error: msg})
+	case msg == "mfa locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: msg})
+	case msg == "mfa code required", msg == "name is too long", msg == "at least one scope is required",
+		strings.HasPrefix(msg, "unknown scope"), strings.HasPrefix(msg, "transfer_limit"),
+		strings.HasSuffix(msg, "must not be empty"), strings.HasSuffix(msg, "must not be negative"):
+		return c.Status(400).JSON(models.ErrorResponse{Error: msg})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: msg})
+	}
+}
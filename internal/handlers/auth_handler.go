@@ -4,25 +4,38 @@ import (
 	"fiber-api/internal/models"
 	"fiber-api/internal/services"
 	"fiber-api/internal/utils"
+	"log"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type AuthHandler struct {
-	userService *services.UserService
-	jwtSecret   []byte
+	userService         *services.UserService
+	mfaService          *services.MFAService
+	loginGuard          *services.LoginGuard
+	verificationService *services.EmailVerificationService
+	sessionService      *services.SessionService
+	jwtSecret           []byte
+	mfaTTL              time.Duration
 }
 
-func NewAuthHandler(userService *services.UserService, jwtSecret []byte) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, mfaService *services.MFAService, loginGuard *services.LoginGuard, verificationService *services.EmailVerificationService, sessionService *services.SessionService, jwtSecret []byte, mfaTTL time.Duration) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtSecret:   jwtSecret,
+		userService:         userService,
+		mfaService:          mfaService,
+		loginGuard:          loginGuard,
+		verificationService: verificationService,
+		sessionService:      sessionService,
+		jwtSecret:           jwtSecret,
+		mfaTTL:              mfaTTL,
 	}
 }
 
 // Register endpoint
 // @Summary User Registration
-// @Description Register a new user account
+// @Description Register a new user account. The password must satisfy the password policy and not appear in a known data breach. A verification link is emailed to the address; until it is followed the account may be restricted, e.g. from sending transfers
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -42,13 +55,23 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return c.Status(400).JSON(models.ErrorResponse{Error: "Email, password, first_name, and last_name are required"})
 	}
 
+	// Clients may identify the device in a header instead of the body
+	if req.DeviceID == "" {
+		req.DeviceID = c.Get("X-Device-ID")
+	}
+
 	user, err := h.userService.CreateUser(req)
 	if err != nil {
 		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID, user.Email, h.jwtSecret)
+	// The account exists either way; the user can ask for another email
+	if err := h.verificationService.Send(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	// Generate token for a new session
+	token, err := startSession(c, h.sessionService, user, req.DeviceName, h.jwtSecret)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{Error: "Failed to generate token"})
 	}
@@ -58,14 +81,16 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 // Login endpoint
 // @Summary User Login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return JWT token. Users with two-factor authentication get 202 and an MFA token to complete at /login/mfa instead. Repeated failures slow down and then lock out further attempts per email and per IP
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param credentials body models.LoginRequest true "User login credentials"
 // @Success 200 {object} models.LoginResponse
+// @Success 202 {object} models.MFAChallengeResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -74,13 +99,118 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
 	}
 
+	// Throttled the same way whether or not the email is registered
+	wait, err := h.loginGuard.Check(req.Email, c.IP())
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+		return c.Status(429).JSON(models.ErrorResponse{Error: "Too many login attempts, try again later"})
+	}
+
 	user, err := h.userService.AuthenticateUser(req)
 	if err != nil {
+		if err.Error() == "invalid credentials" {
+			if err := h.loginGuard.Fail(req.Email, c.IP()); err != nil {
+				return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+			}
+		}
 		return c.Status(401).JSON(models.ErrorResponse{Error: err.Error()})
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID, user.Email, h.jwtSecret)
+	if err := h.loginGuard.Succeed(req.Email); err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	// The password is only the first factor for users with 2FA
+	if user.MFAEnabled {
+		nonce, err := h.mfaService.StartLogin(user.ID, h.mfaTTL)
+		if err != nil {
+			return c.Status(500).JSON(models.ErrorResponse{Error: "Failed to generate token"})
+		}
+		mfaToken, err := utils.GenerateMFAToken(user.ID, user.TokenVersion, nonce, h.jwtSecret, h.mfaTTL)
+		if err != nil {
+			return c.Status(500).JSON(models.ErrorResponse{Error: "Failed to generate token"})
+		}
+		return c.Status(202).JSON(models.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(h.mfaTTL.Seconds()),
+		})
+	}
+
+	// Generate token for a new session
+	token, err := startSession(c, h.sessionService, user, req.DeviceName, h.jwtSecret)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: "Failed to generate token"})
+	}
+
+	return c.JSON(models.LoginResponse{Token: token, User: *user})
+}
+
+// Login MFA endpoint
+// @Summary Complete Two-Factor Login
+// @Description Exchange the MFA token from /login and a TOTP code, or a single-use recovery code, for a JWT token. Each MFA token completes one login, and a password change or reset since /login voids it
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param credentials body models.MFALoginRequest true "MFA token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *fiber.Ctx) error {
+	var req models.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "mfa_token and code or recovery_code are required"})
+	}
+
+	claims, err := utils.ParseToken(req.MFAToken, h.jwtSecret)
+	if err != nil || claims.Purpose != "mfa" || claims.ID == "" {
+		return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid or expired mfa token"})
+	}
+
+	// Like JWTs, the token is void once the token version moves on, e.g.
+	// when the password is reset before the login is finished
+	user, err := h.userService.GetUserByID(claims.UserID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid or expired mfa token"})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+	if claims.Version != user.TokenVersion {
+		return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid or expired mfa token"})
+	}
+
+	if err := h.mfaService.Verify(claims.UserID, req.Code, req.RecoveryCode); err != nil {
+		switch err.Error() {
+		case "invalid code", "mfa not enabled", "user not found":
+			return c.Status(401).JSON(models.ErrorResponse{Error: "invalid code"})
+		case "mfa locked":
+			return c.Status(423).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	// Only the first request with the token gets a session
+	if err := h.mfaService.FinishLogin(user.ID, claims.ID); err != nil {
+		if err.Error() == "invalid or expired mfa token" {
+			return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid or expired mfa token"})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	// Generate token for a new session
+	token, err := startSession(c, h.sessionService, user, req.DeviceName, h.jwtSecret)
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{Error: "Failed to generate token"})
 	}
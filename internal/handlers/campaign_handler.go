@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CampaignHandler struct {
+	campaignService *services.CampaignService
+}
+
+func NewCampaignHandler(campaignService *services.CampaignService) *CampaignHandler {
+	return &CampaignHandler{
+		campaignService: campaignService,
+	}
+}
+
+// Create campaign endpoint
+// @Summary Create Campaign
+// @Description Define a new reward campaign (admin only)
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param campaign body models.CreateCampaignRequest true "Campaign details"
+// @Success 201 {object} models.Campaign
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/campaigns [post]
+func (h *CampaignHandler) CreateCampaign(c *fiber.Ctx) error {
+	var req models.CreateCampaignRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Name == "" || req.Type == "" || req.RewardAmount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "name, type and reward_amount are required"})
+	}
+
+	campaign, err := h.campaignService.CreateCampaign(req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid campaign type", "ends_at must be after starts_at":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.Status(201).JSON(campaign)
+}
+
+// List campaigns endpoint
+// @Summary List Campaigns
+// @Description List all reward campaigns with their budgets and spend (admin only)
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CampaignListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/campaigns [get]
+func (h *CampaignHandler) ListCampaigns(c *fiber.Ctx) error {
+	response, err := h.campaignService.ListCampaigns()
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Update campaign endpoint
+// @Summary Update Campaign
+// @Description Change a campaign's reward, budget, caps, window or active flag (admin only)
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Campaign ID"
+// @Param campaign body models.UpdateCampaignRequest true "Fields to update"
+// @Success 200 {object} models.Campaign
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/campaigns/{id} [patch]
+func (h *CampaignHandler) UpdateCampaign(c *fiber.Ctx) error {
+	campaignID, err := c.ParamsInt("id")
+	if err != nil || campaignID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid campaign id"})
+	}
+
+	var req models.UpdateCampaignRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	campaign, err := h.campaignService.UpdateCampaign(uint(campaignID), req)
+	if err != nil {
+		switch err.Error() {
+		case "campaign not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		case "reward_amount must be positive":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.JSON(campaign)
+}
+
+// Get campaign account endpoint
+// @Summary Get Campaign Account
+// @Description Get the campaign account balance and recent fundings (admin only)
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CampaignAccountResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/campaign-account [get]
+func (h *CampaignHandler) GetAccount(c *fiber.Ctx) error {
+	response, err := h.campaignService.GetAccount()
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Fund campaign account endpoint
+// @Summary Fund Campaign Account
+// @Description Add points to the account all campaigns pay out from (admin only)
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param funding body models.FundCampaignAccountRequest true "Funding details"
+// @Success 200 {object} models.CampaignAccountResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/campaign-account/fund [post]
+func (h *CampaignHandler) FundAccount(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.FundCampaignAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "amount is required"})
+	}
+
+	response, err := h.campaignService.FundAccount(userID, req)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CatalogHandler struct {
+	catalogService *services.CatalogService
+}
+
+func NewCatalogHandler(catalogService *services.CatalogService) *CatalogHandler {
+	return &CatalogHandler{
+		catalogService: catalogService,
+	}
+}
+
+// Get catalog endpoint
+// @Summary Get Rewards Catalog
+// @Description List the rewards that can currently be redeemed for points
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CatalogResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /catalog [get]
+func (h *CatalogHandler) GetCatalog(c *fiber.Ctx) error {
+	response, err := h.catalogService.ListItems(false)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Redeem catalog item endpoint
+// @Summary Redeem Catalog Item
+// @Description Spend points on a catalog item, reserving stock and creating an order
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Catalog item ID"
+// @Param redemption body models.RedeemRequest false "Redemption details"
+// @Success 201 {object} models.RedemptionOrder
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /catalog/{id}/redeem [post]
+func (h *CatalogHandler) Redeem(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	itemID, err := c.ParamsInt("id")
+	if err != nil || itemID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid item id"})
+	}
+
+	var req models.RedeemRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+		}
+	}
+
+	order, err := h.catalogService.Redeem(userID, uint(itemID), req.Quantity)
+	if err != nil {
+		switch msg := err.Error(); {
+		case msg == "item not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		case msg == "insufficient points", msg == "per-user limit reached", msg == "order total too large",
+			strings.HasPrefix(msg, "quantity must be at most"):
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		case msg == "out of stock":
+			return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.Status(201).JSON(order)
+}
+
+// Get orders endpoint
+// @Summary Get Redemption Orders
+// @Description List the authenticated user's redemption orders
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.OrderListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /orders [get]
+func (h *CatalogHandler) GetOrders(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.catalogService.ListOrders(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Cancel order endpoint
+// @Summary Cancel Redemption Order
+// @Description Cancel a pending redemption order, returning the points and the stock
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.RedemptionOrder
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /orders/{id}/cancel [post]
+func (h *CatalogHandler) CancelOrder(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid order id"})
+	}
+
+	order, err := h.catalogService.CancelOrder(userID, uint(orderID))
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.JSON(order)
+}
+
+// Create catalog item endpoint
+// @Summary Create Catalog Item
+// @Description Add a reward to the catalog (admin only)
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param item body models.CreateCatalogItemRequest true "Catalog item details"
+// @Success 201 {object} models.CatalogItem
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/catalog [post]
+func (h *CatalogHandler) CreateItem(c *fiber.Ctx) error {
+	var req models.CreateCatalogItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Name == "" || req.PointPrice == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "name and point_price are required"})
+	}
+
+	item, err := h.catalogService.CreateItem(req)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(201).JSON(item)
+}
+
+// List catalog items endpoint
+// @Summary List Catalog Items
+// @Description List every catalog item including inactive ones (admin only)
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CatalogResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/catalog [get]
+func (h *CatalogHandler) ListItems(c *fiber.Ctx) error {
+	response, err := h.catalogService.ListItems(true)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Update catalog item endpoint
+// @Summary Update Catalog Item
+// @Description Change a catalog item's details, price, stock, limit or active flag (admin only)
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Catalog item ID"
+// @Param item body models.UpdateCatalogItemRequest true "Fields to update"
+// @Success 200 {object} models.CatalogItem
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/catalog/{id} [patch]
+func (h *CatalogHandler) UpdateItem(c *fiber.Ctx) error {
+	itemID, err := c.ParamsInt("id")
+	if err != nil || itemID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid item id"})
+	}
+
+	var req models.UpdateCatalogItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	item, err := h.catalogService.UpdateItem(uint(itemID), req)
+	if err != nil {
+		switch err.Error() {
+		case "item not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		case "point_price must be positive":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.JSON(item)
+}
+
+// List all orders endpoint
+// @Summary List All Redemption Orders
+// @Description List redemption orders of all users, optionally filtered by status (admin only)
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Order status"
+// @Success 200 {object} models.OrderListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/orders [get]
+func (h *CatalogHandler) ListAllOrders(c *fiber.Ctx) error {
+	response, err := h.catalogService.ListAllOrders(c.Query("status"))
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Update order status endpoint
+// @Summary Update Redemption Order Status
+// @Description Move an order from pending to shipped or from shipped to fulfilled, or cancel a pending or shipped order and return the points (admin only). Fulfilled and cancelled orders cannot change
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param status body models.UpdateOrderStatusRequest true "New status"
+// @Success 200 {object} models.RedemptionOrder
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/orders/{id} [patch]
+func (h *CatalogHandler) UpdateOrderStatus(c *fiber.Ctx) error {
+	orderID, err := c.ParamsInt("id")
+	if err != nil || orderID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid order id"})
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	order, err := h.catalogService.UpdateOrderStatus(uint(orderID), req.Status)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.JSON(order)
+}
+
+// orderError maps order status change errors to HTTP responses
+func orderError(c *fiber.Ctx, err error) error {
+	switch msg := err.Error(); {
+	case msg == "order not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case msg == "invalid order status":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case msg == "order already cancelled", msg == "order can no longer be cancelled",
+		msg == "order was changed, try again", strings.HasPrefix(msg, "order cannot move from"):
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
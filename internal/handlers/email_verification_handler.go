@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailVerificationHandler struct {
+	verificationService *services.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(verificationService *services.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		verificationService: verificationService,
+	}
+}
+
+// Verify email endpoint
+// @Summary Verify Email Address
+// @Description Confirm the email address with the token from the verification email
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /email/verify [post]
+func (h *EmailVerificationHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req models.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Token == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "token is required"})
+	}
+
+	user, err := h.verificationService.Verify(req.Token)
+	if err != nil {
+		return verificationError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+// Resend verification endpoint
+// @Summary Resend Verification Email
+// @Description Email a new verification link to the authenticated user. Limited to one email per cooldown period and a few per day
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} models.MessageResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/email/verification [post]
+func (h *EmailVerificationHandler) ResendVerification(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	if err := h.verificationService.Resend(userID); err != nil {
+		return verificationError(c, err)
+	}
+
+	return c.Status(202).JSON(models.MessageResponse{Message: "Verification email sent"})
+}
+
+func verificationError(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "invalid or expired token":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "email already verified":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	case "verification email recently sent", "too many verification emails":
+		return c.Status(429).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
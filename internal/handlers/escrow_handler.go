@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EscrowHandler struct {
+	escrowService *services.EscrowService
+}
+
+func NewEscrowHandler(escrowService *services.EscrowService) *EscrowHandler {
+	return &EscrowHandler{
+		escrowService: escrowService,
+	}
+}
+
+// Create escrow endpoint
+// @Summary Create Escrow Transfer
+// @Description Take points from the authenticated user and hold them for the recipient until the sender releases them. Held points are refunded when the escrow expires. Escrows of at least TRANSFER_PIN_THRESHOLD points need the transaction PIN
+// @Tags Escrow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param escrow body models.CreateEscrowRequest true "Escrow details"
+// @Success 201 {object} models.Escrow
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /escrows [post]
+func (h *EscrowHandler) CreateEscrow(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.CreateEscrowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.ToLBKCode == "" || req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "to_lbk_code and amount are required"})
+	}
+
+	escrow, err := h.escrowService.Create(userID, req)
+	if err != nil {
+		return escrowError(c, err)
+	}
+
+	return c.Status(201).JSON(escrow)
+}
+
+// Get escrows endpoint
+// @Summary Get Escrows
+// @Description List the escrows the authenticated user is sending or receiving
+// @Tags Escrow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.EscrowListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /escrows [get]
+func (h *EscrowHandler) GetEscrows(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.escrowService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Release escrow endpoint
+// @Summary Release Escrow
+// @Description Pay the held points to the recipient once the delivery has been received. Only the sender can release
+// @Tags Escrow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Escrow ID"
+// @Success 200 {object} models.Escrow
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /escrows/{id}/release [post]
+func (h *EscrowHandler) ReleaseEscrow(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	escrowID, err := c.ParamsInt("id")
+	if err != nil || escrowID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid escrow id"})
+	}
+
+	escrow, err := h.escrowService.Release(userID, uint(escrowID))
+	if err != nil {
+		return escrowError(c, err)
+	}
+
+	return c.JSON(escrow)
+}
+
+// Dispute escrow endpoint
+// @Summary Dispute Escrow
+// @Description Freeze a held escrow until an admin resolves it. Either party can open a dispute
+// @Tags Escrow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Escrow ID"
+// @Param dispute body models.DisputeEscrowRequest true "Dispute reason"
+// @Success 200 {object} models.Escrow
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /escrows/{id}/dispute [post]
+func (h *EscrowHandler) DisputeEscrow(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	escrowID, err := c.ParamsInt("id")
+	if err != nil || escrowID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid escrow id"})
+	}
+
+	var req models.DisputeEscrowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Reason == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "reason is required"})
+	}
+
+	escrow, err := h.escrowService.Dispute(userID, uint(escrowID), req.Reason)
+	if err != nil {
+		return escrowError(c, err)
+	}
+
+	return c.JSON(escrow)
+}
+
+// List all escrows endpoint
+// @Summary List All Escrows
+// @Description List escrows of all users, optionally filtered by status (admin only)
+// @Tags Escrow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Escrow status"
+// @Success 200 {object} models.EscrowListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/escrows [get]
+func (h *EscrowHandler) ListAllEscrows(c *fiber.Ctx) error {
+	response, err := h.escrowService.ListAll(c.Query("status"))
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Resolve escrow endpoint
+// @Summary Resolve Escrow Dispute
+// @Description Settle a disputed escrow by releasing the points to the recipient or refunding the sender (admin only)
+// @Tags Escrow
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Escrow ID"
+// @Param resolution body models.ResolveEscrowRequest true "Resolution"
+// @Success 200 {object} models.Escrow
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/escrows/{id}/resolve [post]
+func (h *EscrowHandler) ResolveEscrow(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	escrowID, err := c.ParamsInt("id")
+	if err != nil || escrowID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid escrow id"})
+	}
+
+	var req models.ResolveEscrowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	escrow, err := h.escrowService.Resolve(adminID, uint(escrowID), req)
+	if err != nil {
+		return escrowError(c, err)
+	}
+
+	return c.JSON(escrow)
+}
+
+// escrowError maps escrow errors to HTTP responses
+func escrowError(c *fiber.Ctx, err error) error {
+	switch msg := err.Error(); {
+	case msg == "escrow not found", msg == "recipient user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: msg})
+	case msg == "insufficient points", msg == "unknown currency", msg == "cannot transfer points to yourself",
+		msg == "invalid resolution", strings.HasPrefix(msg, "expires_in_hours"):
+		return c.Status(400).JSON(models.ErrorResponse{Error: msg})
+	case msg == "pin required", msg == "pin not set", msg == "invalid pin":
+		return c.Status(403).JSON(models.ErrorResponse{Error: msg})
+	case msg == "escrow is no longer held", msg == "escrow is no longer open":
+		return c.Status(409).JSON(models.ErrorResponse{Error: msg})
+	case msg == "pin locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: msg})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: msg})
+	}
+}
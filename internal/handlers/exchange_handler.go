@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExchangeHandler struct {
+	exchangeService *services.ExchangeService
+}
+
+func NewExchangeHandler(exchangeService *services.ExchangeService) *ExchangeHandler {
+	return &ExchangeHandler{
+		exchangeService: exchangeService,
+	}
+}
+
+// Get exchange rates endpoint
+// @Summary Get Exchange Rates
+// @Description List the exchange rates currently in effect between currencies
+// @Tags Exchange
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ExchangeRateListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /exchange-rates [get]
+func (h *ExchangeHandler) GetRates(c *fiber.Ctx) error {
+	response, err := h.exchangeService.ListRates(true)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// List exchange rates endpoint
+// @Summary List Exchange Rates
+// @Description List every exchange rate including past and scheduled ones (admin only)
+// @Tags Exchange
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ExchangeRateListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/exchange-rates [get]
+func (h *ExchangeHandler) ListRates(c *fiber.Ctx) error {
+	response, err := h.exchangeService.ListRates(false)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Create exchange rate endpoint
+// @Summary Create Exchange Rate
+// @Description Set the rate between two currencies from a given date; it replaces the previous rate once effective (admin only)
+// @Tags Exchange
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rate body models.CreateExchangeRateRequest true "Rate details"
+// @Success 201 {object} models.ExchangeRate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/exchange-rates [post]
+func (h *ExchangeHandler) CreateRate(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	var req models.CreateExchangeRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.FromCurrency == "" || req.ToCurrency == "" || req.Rate == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "from_currency, to_currency and rate are required"})
+	}
+
+	rate, err := h.exchangeService.CreateRate(adminID, req)
+	if err != nil {
+		switch err.Error() {
+		case "currencies must differ", "unknown currency", "rate must be a positive decimal with up to 6 places":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.Status(201).JSON(rate)
+}
+
+// Quote conversion endpoint
+// @Summary Quote Conversion
+// @Description Lock the current exchange rate for converting points between two of the user's wallets. The quote can be executed until it expires
+// @Tags Exchange
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param quote body models.ConversionQuoteRequest true "Conversion details"
+// @Success 201 {object} models.Conversion
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /conversions/quote [post]
+func (h *ExchangeHandler) Quote(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.ConversionQuoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.FromCurrency == "" || req.ToCurrency == "" || req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "from_currency, to_currency and amount are required"})
+	}
+
+	quote, err := h.exchangeService.Quote(userID, req)
+	if err != nil {
+		return conversionError(c, err)
+	}
+
+	return c.Status(201).JSON(quote)
+}
+
+// Execute conversion endpoint
+// @Summary Execute Conversion
+// @Description Convert points at the rate locked by a quote
+// @Tags Exchange
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Quote ID"
+// @Success 200 {object} models.Conversion
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /conversions/{id}/execute [post]
+func (h *ExchangeHandler) Execute(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	conversion, err := h.exchangeService.Execute(userID, c.Params("id"))
+	if err != nil {
+		return conversionError(c, err)
+	}
+
+	return c.JSON(conversion)
+}
+
+// conversionError maps quote and conversion errors to HTTP responses
+func conversionError(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "quote not found", "no exchange rate for these currencies":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "currencies must differ", "unknown currency", "insufficient points",
+		"amount too small to convert", "amount too large to convert":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "quote is no longer open", "quote expired":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MerchantHandler struct {
+	merchantService *services.MerchantService
+}
+
+func NewMerchantHandler(merchantService *services.MerchantService) *MerchantHandler {
+	return &MerchantHandler{
+		merchantService: merchantService,
+	}
+}
+
+// Create merchant endpoint
+// @Summary Create Merchant
+// @Description Register a merchant account that can accept point payments (admin only)
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param merchant body models.CreateMerchantRequest true "Merchant details"
+// @Success 201 {object} models.Merchant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/merchants [post]
+func (h *MerchantHandler) CreateMerchant(c *fiber.Ctx) error {
+	var req models.CreateMerchantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Name == "" || req.Email == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "name and email are required"})
+	}
+
+	merchant, err := h.merchantService.CreateMerchant(req)
+	if err != nil {
+		if err.Error() == "merchant already exists" {
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(201).JSON(merchant)
+}
+
+// List merchants endpoint
+// @Summary List Merchants
+// @Description List all merchant accounts with their settled balances (admin only)
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MerchantListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/merchants [get]
+func (h *MerchantHandler) ListMerchants(c *fiber.Ctx) error {
+	response, err := h.merchantService.ListMerchants()
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Create merchant API key endpoint
+// @Summary Create Merchant API Key
+// @Description Issue an API key for a merchant. The key is only returned once (admin only)
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Merchant ID"
+// @Param key body models.CreateAPIKeyRequest false "Key label"
+// @Success 201 {object} models.APIKeyCreatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/merchants/{id}/api-keys [post]
+func (h *MerchantHandler) CreateAPIKey(c *fiber.Ctx) error {
+	merchantID, err := c.ParamsInt("id")
+	if err != nil || merchantID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid merchant id"})
+	}
+
+	var req models.CreateAPIKeyRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+		}
+	}
+
+	response, err := h.merchantService.CreateAPIKey(uint(merchantID), req.Name)
+	if err != nil {
+		if err.Error() == "merchant not found" {
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(201).JSON(response)
+}
+
+// Revoke merchant API key endpoint
+// @Summary Revoke Merchant API Key
+// @Description Revoke one of a merchant's API keys (admin only)
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Merchant ID"
+// @Param keyId path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/merchants/{id}/api-keys/{keyId} [delete]
+func (h *MerchantHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	merchantID, err := c.ParamsInt("id")
+	if err != nil || merchantID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid merchant id"})
+	}
+	keyID, err := c.ParamsInt("keyId")
+	if err != nil || keyID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid api key id"})
+	}
+
+	if err := h.merchantService.RevokeAPIKey(uint(merchantID), uint(keyID)); err != nil {
+		if err.Error() == "api key not found" {
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.SendStatus(204)
+}
+
+// Create payment intent endpoint
+// @Summary Create Payment Intent
+// @Description Request a point payment from a customer. Authenticated with a merchant API key
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Merchant API key"
+// @Param intent body models.CreatePaymentIntentRequest true "Payment details"
+// @Success 201 {object} models.PaymentIntent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /merchant/payment-intents [post]
+func (h *MerchantHandler) CreatePaymentIntent(c *fiber.Ctx) error {
+	merchantID := c.Locals("merchantID").(uint)
+
+	var req models.CreatePaymentIntentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "amount is required"})
+	}
+
+	intent, err := h.merchantService.CreatePaymentIntent(merchantID, req)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(201).JSON(intent)
+}
+
+// Get merchant payment intent endpoint
+// @Summary Get Payment Intent (Merchant)
+// @Description Get the status of one of the merchant's payment intents
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Merchant API key"
+// @Param id path string true "Payment intent ID"
+// @Success 200 {object} models.PaymentIntent
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /merchant/payment-intents/{id} [get]
+func (h *MerchantHandler) GetMerchantIntent(c *fiber.Ctx) error {
+	merchantID := c.Locals("merchantID").(uint)
+
+	intent, err := h.merchantService.GetMerchantIntent(merchantID, c.Params("id"))
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(intent)
+}
+
+// Cancel payment intent endpoint
+// @Summary Cancel Payment Intent
+// @Description Cancel a payment intent that has not been confirmed yet
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Merchant API key"
+// @Param id path string true "Payment intent ID"
+// @Success 200 {object} models.PaymentIntent
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /merchant/payment-intents/{id}/cancel [post]
+func (h *MerchantHandler) CancelPaymentIntent(c *fiber.Ctx) error {
+	merchantID := c.Locals("merchantID").(uint)
+
+	intent, err := h.merchantService.CancelPaymentIntent(merchantID, c.Params("id"))
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(intent)
+}
+
+// Get merchant transactions endpoint
+// @Summary Get Merchant Transactions
+// @Description List the merchant's settled payments and current balance
+// @Tags Merchants
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Merchant API key"
+// @Success 200 {object} models.MerchantTransactionsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /merchant/transactions [get]
+func (h *MerchantHandler) GetTransactions(c *fiber.Ctx) error {
+	merchantID := c.Locals("merchantID").(uint)
+
+	response, err := h.merchantService.GetTransactions(merchantID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Get payment endpoint
+// @Summary Get Payment
+// @Description Get a merchant's payment request before confirming it
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment intent ID"
+// @Success 200 {object} models.PaymentIntentResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payments/{id} [get]
+func (h *MerchantHandler) GetPayment(c *fiber.Ctx) error {
+	response, err := h.merchantService.GetPaymentIntent(c.Params("id"))
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(response)
+}
+
+// Confirm payment endpoint
+// @Summary Confirm Payment
+// @Description Pay a merchant's payment request with the authenticated user's points. Payments of at least TRANSFER_PIN_THRESHOLD points need the transaction PIN
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment intent ID"
+// @Param pin body models.ConfirmPaymentRequest false "Transaction PIN for high-value payments"
+// @Success 200 {object} models.PaymentIntentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payments/{id}/confirm [post]
+func (h *MerchantHandler) ConfirmPayment(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	// The body is optional, it only carries the PIN
+	var req models.ConfirmPaymentRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+		}
+	}
+
+	response, err := h.merchantService.ConfirmPaymentIntent(userID, c.Params("id"), req.PIN)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(response)
+}
+
+// paymentError maps payment intent errors to HTTP responses
+func paymentError(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "payment intent not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "insufficient points", "merchant is not accepting payments":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "pin required", "pin not set", "invalid pin":
+		return c.Status(403).JSON(models.ErrorResponse{Error: err.Error()})
+	case "payment intent is no longer open", "payment intent expired":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	case "pin locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"fiber-api/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MFAHandler struct {
+	mfaService *services.MFAService
+}
+
+func NewMFAHandler(mfaService *services.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// Enroll MFA endpoint
+// @Summary Enroll Two-Factor Authentication
+// @Description Generate a TOTP secret for an authenticator app, as an otpauth URI and QR code. 2FA is enabled once a code is confirmed
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.EnrollMFARequest true "Account password"
+// @Success 200 {object} models.MFAEnrollmentResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.EnrollMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Password == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "password is required"})
+	}
+
+	enrollment, err := h.mfaService.Enroll(userID, req.Password, c.IP())
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	image, err := utils.RenderQRPNG(enrollment.OTPAuthURI, 256)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: "failed to render qr code"})
+	}
+	enrollment.QRCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)
+
+	return c.JSON(enrollment)
+}
+
+// Confirm MFA endpoint
+// @Summary Confirm Two-Factor Authentication
+// @Description Enable 2FA with a code from the authenticator app. Returns recovery codes, which are not shown again
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Code == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "code is required"})
+	}
+
+	codes, err := h.mfaService.Confirm(userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(codes)
+}
+
+// Regenerate recovery codes endpoint
+// @Summary Regenerate Recovery Codes
+// @Description Replace all recovery codes with a new set, confirmed with a TOTP code
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Code == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "code is required"})
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(codes)
+}
+
+// Disable MFA endpoint
+// @Summary Disable Two-Factor Authentication
+// @Description Turn 2FA off, confirmed with the account password and a TOTP code
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DisableMFARequest true "Password and TOTP code"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/mfa/disable [post]
+func (h *MFAHandler) Disable(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.DisableMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Password == "" || req.Code == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "password and code are required"})
+	}
+
+	user, err := h.mfaService.Disable(userID, req, c.IP())
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+// Reset MFA endpoint
+// @Summary Reset Two-Factor Authentication
+// @Description Turn 2FA off for a user who lost their authenticator and recovery codes (admin only)
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/mfa/reset [post]
+func (h *MFAHandler) ResetMFA(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid user id"})
+	}
+
+	user, err := h.mfaService.Reset(uint(userID))
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+// mfaError maps two-factor authentication errors to HTTP responses
+func mfaError(c *fiber.Ctx, err error) error {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		return tooManyAttempts(c, throttled)
+	}
+
+	switch err.Error() {
+	case "user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "mfa not enrolled", "mfa not enabled":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "invalid password", "invalid code":
+		return c.Status(401).JSON(models.ErrorResponse{Error: err.Error()})
+	case "mfa already enabled":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	case "mfa locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// Get notifications endpoint
+// @Summary Get Notifications
+// @Description List the latest notifications of the authenticated user, newest first
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.NotificationListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications [get]
+func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.notificationService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Mark notifications read endpoint
+// @Summary Mark Notifications Read
+// @Description Mark every unread notification of the authenticated user as read and return the updated list
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.NotificationListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /notifications/read [post]
+func (h *NotificationHandler) MarkNotificationsRead(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	if err := h.notificationService.MarkAllRead(userID); err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := h.notificationService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
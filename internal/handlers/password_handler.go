@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PasswordHandler struct {
+	resetService *services.PasswordResetService
+}
+
+func NewPasswordHandler(resetService *services.PasswordResetService) *PasswordHandler {
+	return &PasswordHandler{
+		resetService: resetService,
+	}
+}
+
+// Forgot password endpoint
+// @Summary Request Password Reset
+// @Description Email a single-use password reset link. The response is the same whether or not the email is registered
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 202 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Email == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "email is required"})
+	}
+
+	if err := h.resetService.Forgot(req.Email, c.IP()); err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.Status(202).JSON(models.MessageResponse{Message: "If the email is registered, a reset link has been sent"})
+}
+
+// Reset password endpoint
+// @Summary Reset Password
+// @Description Set a new password with the token from the reset email. The password must satisfy the password policy. The token works once, every existing session is signed out and every API key revoked
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /password/reset [post]
+func (h *PasswordHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Token == "" || req.Password == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "token and password are required"})
+	}
+
+	if err := h.resetService.Reset(req, c.IP()); err != nil {
+		switch msg := err.Error(); {
+		case msg == "invalid or expired token", isPasswordPolicyError(msg):
+			return c.Status(400).JSON(models.ErrorResponse{Error: msg})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.JSON(models.MessageResponse{Message: "Password has been reset"})
+}
+
+// isPasswordPolicyError reports whether msg is a new password being rejected
+// by the password policy
+func isPasswordPolicyError(msg string) bool {
+	return strings.HasPrefix(msg, "password must ") || msg == "password has appeared in a data breach"
+}
+
+// tooManyAttempts answers a re-entered password that the login guard throttles
+func tooManyAttempts(c *fiber.Ctx, err *services.ThrottledError) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(err.Wait.Seconds())+1))
+	return c.Status(429).JSON(models.ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentRequestHandler struct {
+	paymentRequestService *services.PaymentRequestService
+	transferService       *services.TransferService
+}
+
+func NewPaymentRequestHandler(paymentRequestService *services.PaymentRequestService, transferService *services.TransferService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{
+		paymentRequestService: paymentRequestService,
+		transferService:       transferService,
+	}
+}
+
+// Create payment request endpoint
+// @Summary Create Payment Request
+// @Description Ask several users to pay their share of a bill. The total is split evenly, optionally counting the requester, or by custom shares. Every participant is notified
+// @Tags Payment Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreatePaymentRequestRequest true "Bill details"
+// @Success 201 {object} models.PaymentRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-requests [post]
+func (h *PaymentRequestHandler) CreatePaymentRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.CreatePaymentRequestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Title == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "title is required"})
+	}
+
+	request, err := h.paymentRequestService.Create(userID, req)
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.Status(201).JSON(request)
+}
+
+// Get payment requests endpoint
+// @Summary Get Payment Requests
+// @Description List the payment requests the authenticated user created or has a share in
+// @Tags Payment Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PaymentRequestListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-requests [get]
+func (h *PaymentRequestHandler) GetPaymentRequests(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.paymentRequestService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Get payment request endpoint
+// @Summary Get Payment Request
+// @Description Get a payment request with the status of every share
+// @Tags Payment Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment request ID"
+// @Success 200 {object} models.PaymentRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-requests/{id} [get]
+func (h *PaymentRequestHandler) GetPaymentRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	requestID, err := c.ParamsInt("id")
+	if err != nil || requestID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid payment request id"})
+	}
+
+	request, err := h.paymentRequestService.Get(userID, uint(requestID))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.JSON(request)
+}
+
+// Pay share endpoint
+// @Summary Pay Payment Request Share
+// @Description Transfer the authenticated user's share to the requester. The same can be done with POST /points/transfer and payment_request_id
+// @Tags Payment Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment request ID"
+// @Param pin body models.PayShareRequest false "Transaction PIN for high-value shares"
+// @Success 200 {object} models.TransferResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-requests/{id}/pay [post]
+func (h *PaymentRequestHandler) PayShare(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	requestID, err := c.ParamsInt("id")
+	if err != nil || requestID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid payment request id"})
+	}
+
+	// The body is optional, it only carries the PIN
+	var req models.PayShareRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+		}
+	}
+
+	transfer, err := h.paymentRequestService.ShareTransfer(userID, uint(requestID))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+	transfer.PIN = req.PIN
+
+	response, err := h.transferService.TransferPoints(userID, *transfer)
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.JSON(response)
+}
+
+// Remind participants endpoint
+// @Summary Remind Participants
+// @Description Notify everyone who has not paid their share yet. Participants reminded recently are skipped
+// @Tags Payment Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment request ID"
+// @Success 200 {object} models.RemindersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-requests/{id}/remind [post]
+func (h *PaymentRequestHandler) RemindParticipants(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	requestID, err := c.ParamsInt("id")
+	if err != nil || requestID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid payment request id"})
+	}
+
+	reminded, err := h.paymentRequestService.Remind(userID, uint(requestID))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.JSON(models.RemindersResponse{Reminded: reminded})
+}
+
+// Cancel payment request endpoint
+// @Summary Cancel Payment Request
+// @Description Close an open payment request. Shares already paid are not refunded
+// @Tags Payment Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment request ID"
+// @Success 200 {object} models.PaymentRequest
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-requests/{id}/cancel [post]
+func (h *PaymentRequestHandler) CancelPaymentRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	requestID, err := c.ParamsInt("id")
+	if err != nil || requestID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid payment request id"})
+	}
+
+	request, err := h.paymentRequestService.Cancel(userID, uint(requestID))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.JSON(request)
+}
+
+// paymentRequestError maps payment request and transfer errors to HTTP responses
+func paymentRequestError(c *fiber.Ctx, err error) error {
+	switch msg := err.Error(); {
+	case msg == "payment request not found", msg == "recipient user not found",
+		strings.HasPrefix(msg, "participant not found"):
+		return c.Status(404).JSON(models.ErrorResponse{Error: msg})
+	case msg == "payment request is closed", msg == "share already paid":
+		return c.Status(409).JSON(models.ErrorResponse{Error: msg})
+	case msg == "pin required", msg == "pin not set", msg == "invalid pin":
+		return c.Status(403).JSON(models.ErrorResponse{Error: msg})
+	case msg == "pin locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: msg})
+	case msg == "insufficient points", msg == "unknown currency", msg == "cannot transfer points to yourself",
+		msg == "total_amount is too small to split", msg == "every share needs an amount",
+		msg == "split must be even or custom", msg == "at least one participant is required",
+		strings.HasPrefix(msg, "at most"), msg == "participants must be unique",
+		msg == "cannot request payment from yourself", msg == "transfer must go to the requester",
+		msg == "currency does not match payment request", msg == "you have no share in this payment request",
+		msg == "amount does not match your share":
+		return c.Status(400).JSON(models.ErrorResponse{Error: msg})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: msg})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PhoneHandler struct {
+	phoneService *services.PhoneService
+}
+
+func NewPhoneHandler(phoneService *services.PhoneService) *PhoneHandler {
+	return &PhoneHandler{
+		phoneService: phoneService,
+	}
+}
+
+// Change phone number endpoint
+// @Summary Change Phone Number
+// @Description Set a new phone number, normalized to E.164, and text it a verification code. The number is unverified until the code is confirmed
+// @Tags Phone
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param phone body models.ChangePhoneRequest true "New phone number"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/phone [put]
+func (h *PhoneHandler) ChangePhone(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.ChangePhoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.PhoneNumber == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "phone_number is required"})
+	}
+
+	user, err := h.phoneService.ChangeNumber(userID, req.PhoneNumber, c.IP())
+	if err != nil {
+		return phoneError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+// Send phone code endpoint
+// @Summary Send Phone Verification Code
+// @Description Text a new verification code to the authenticated user's phone number
+// @Tags Phone
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/phone/code [post]
+func (h *PhoneHandler) SendCode(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	if err := h.phoneService.SendCode(userID); err != nil {
+		return phoneError(c, err)
+	}
+
+	return c.Status(202).JSON(models.MessageResponse{Message: "Verification code sent"})
+}
+
+// Verify phone endpoint
+// @Summary Verify Phone Number
+// @Description Confirm the phone number with the texted code. A number can be verified by one user only
+// @Tags Phone
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body models.VerifyPhoneRequest true "Verification code"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/phone/verify [post]
+func (h *PhoneHandler) VerifyPhone(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.VerifyPhoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Code == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "code is required"})
+	}
+
+	user, err := h.phoneService.Verify(userID, req.Code)
+	if err != nil {
+		return phoneError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+func phoneError(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "invalid phone number", "no phone number", "no code sent", "code expired", "too many attempts", "invalid code":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "phone number already verified", "phone number already in use":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	case "code recently sent", "too many codes requested":
+		return c.Status(429).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
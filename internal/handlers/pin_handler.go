@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PINHandler struct {
+	pinService *services.PINService
+}
+
+func NewPINHandler(pinService *services.PINService) *PINHandler {
+	return &PINHandler{
+		pinService: pinService,
+	}
+}
+
+// Get PIN status endpoint
+// @Summary Get Transaction PIN Status
+// @Description Whether the authenticated user has a transaction PIN, the amount from which transfers need it and any lockout
+// @Tags PIN
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PINStatusResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/pin [get]
+func (h *PINHandler) GetPINStatus(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	status, err := h.pinService.Status(userID)
+	if err != nil {
+		return pinError(c, err)
+	}
+
+	return c.JSON(status)
+}
+
+// Set PIN endpoint
+// @Summary Set Transaction PIN
+// @Description Create the 6 digit transaction PIN, confirmed with the account password
+// @Tags PIN
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pin body models.SetPINRequest true "Password and new PIN"
+// @Success 200 {object} models.PINStatusResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/pin [post]
+func (h *PINHandler) SetPIN(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.SetPINRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Password == "" || req.PIN == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "password and pin are required"})
+	}
+
+	if err := h.pinService.Set(userID, req, c.IP()); err != nil {
+		return pinError(c, err)
+	}
+
+	return h.GetPINStatus(c)
+}
+
+// Change PIN endpoint
+// @Summary Change Transaction PIN
+// @Description Replace the transaction PIN. Wrong current PINs count towards the lockout
+// @Tags PIN
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pin body models.ChangePINRequest true "Current and new PIN"
+// @Success 200 {object} models.PINStatusResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/pin [put]
+func (h *PINHandler) ChangePIN(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.ChangePINRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.CurrentPIN == "" || req.NewPIN == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "current_pin and new_pin are required"})
+	}
+
+	if err := h.pinService.Change(userID, req); err != nil {
+		return pinError(c, err)
+	}
+
+	return h.GetPINStatus(c)
+}
+
+// Reset PIN endpoint
+// @Summary Reset Transaction PIN
+// @Description Replace a forgotten or locked transaction PIN using the account password
+// @Tags PIN
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pin body models.ResetPINRequest true "Password and new PIN"
+// @Success 200 {object} models.PINStatusResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/pin/reset [post]
+func (h *PINHandler) ResetPIN(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.ResetPINRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Password == "" || req.NewPIN == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "password and new_pin are required"})
+	}
+
+	if err := h.pinService.Reset(userID, req, c.IP()); err != nil {
+		return pinError(c, err)
+	}
+
+	return h.GetPINStatus(c)
+}
+
+// pinError maps transaction PIN errors to HTTP responses
+func pinError(c *fiber.Ctx, err error) error {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		return tooManyAttempts(c, throttled)
+	}
+
+	switch err.Error() {
+	case "user not found", "pin not set":
+		return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+	case "pin must be 6 digits":
+		return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+	case "invalid password":
+		return c.Status(401).JSON(models.ErrorResponse{Error: err.Error()})
+	case "invalid pin":
+		return c.Status(403).JSON(models.ErrorResponse{Error: err.Error()})
+	case "pin already set":
+		return c.Status(409).JSON(models.ErrorResponse{Error: err.Error()})
+	case "pin locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: err.Error()})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PointHandler struct {
+	pointExpiryService *services.PointExpiryService
+	expiringSoonDays   int
+}
+
+func NewPointHandler(pointExpiryService *services.PointExpiryService, expiringSoonDays int) *PointHandler {
+	return &PointHandler{
+		pointExpiryService: pointExpiryService,
+		expiringSoonDays:   expiringSoonDays,
+	}
+}
+
+// Get expiring points endpoint
+// @Summary Get Expiring Points
+// @Description Get the authenticated user's point lots that expire within the given number of days
+// @Tags Points
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Look-ahead window in days"
+// @Param currency query string false "Currency code (default PTS)"
+// @Success 200 {object} models.ExpiringPointsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /points/expiring [get]
+func (h *PointHandler) GetExpiringPoints(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	days := c.QueryInt("days", h.expiringSoonDays)
+	if days < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "days must be a positive number"})
+	}
+
+	response, err := h.pointExpiryService.GetExpiringSoon(userID, queryCurrency(c), days)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
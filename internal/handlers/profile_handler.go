@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"fiber-api/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProfileHandler struct {
+	profileService *services.ProfileService
+	sessionService *services.SessionService
+	jwtSecret      []byte
+}
+
+func NewProfileHandler(profileService *services.ProfileService, sessionService *services.SessionService, jwtSecret []byte) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		sessionService: sessionService,
+		jwtSecret:      jwtSecret,
+	}
+}
+
+// Update profile endpoint
+// @Summary Update User Profile
+// @Description Change the name, phone number or date of birth of the authenticated user. Only fields present in the body are changed; a new phone number must be verified again
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body models.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me [patch]
+func (h *ProfileHandler) UpdateProfile(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	user, err := h.profileService.Update(userID, req, c.IP())
+	if err != nil {
+		return profileError(c, err)
+	}
+
+	return c.JSON(user)
+}
+
+// Change password endpoint
+// @Summary Change Password
+// @Description Replace the password after checking the current one. The new password must satisfy the password policy. Wrong current passwords count as failed logins and are throttled the same way. Every other session is signed out and every API key revoked, and a new token is returned for the current one
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param password body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/password [post]
+func (h *ProfileHandler) ChangePassword(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID, _ := c.Locals("sessionID").(uint)
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "current_password and new_password are required"})
+	}
+
+	user, err := h.profileService.ChangePassword(userID, sessionID, req, c.IP())
+	if err != nil {
+		return profileError(c, err)
+	}
+
+	// The old token was revoked with every other session; this one stays signed
+	// in. A token from before sessions were tracked gets a session of its own,
+	// so the new token can be listed and revoked like any other.
+	var token string
+	if sessionID == 0 {
+		token, err = startSession(c, h.sessionService, user, "", h.jwtSecret)
+	} else {
+		token, err = utils.GenerateToken(user.ID, user.Email, user.Role, user.TokenVersion, sessionID, h.jwtSecret)
+	}
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: "Failed to generate token"})
+	}
+
+	return c.JSON(models.LoginResponse{Token: token, User: *user})
+}
+
+// List profile changes endpoint
+// @Summary List Profile Changes
+// @Description Changes to a user's profile and password, newest first, for support (admin only)
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.ProfileChangeListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/profile-changes [get]
+func (h *ProfileHandler) ListProfileChanges(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid user ID"})
+	}
+
+	changes, err := h.profileService.History(uint(userID))
+	if err != nil {
+		return profileError(c, err)
+	}
+
+	return c.JSON(changes)
+}
+
+func profileError(c *fiber.Ctx, err error) error {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		return tooManyAttempts(c, throttled)
+	}
+
+	switch msg := err.Error(); {
+	case msg == "invalid password":
+		return c.Status(401).JSON(models.ErrorResponse{Error: msg})
+	case msg == "user not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: msg})
+	case msg == "phone number already in use":
+		return c.Status(409).JSON(models.ErrorResponse{Error: msg})
+	case msg == "invalid phone number", msg == "dob is out of range", isPasswordPolicyError(msg),
+		msg == "new password must be different", strings.HasPrefix(msg, "invalid date format"),
+		strings.HasSuffix(msg, "must not be empty"), strings.HasSuffix(msg, "is too long"):
+		return c.Status(400).JSON(models.ErrorResponse{Error: msg})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: msg})
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"fiber-api/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type QRHandler struct {
+	qrService *services.QRService
+}
+
+func NewQRHandler(qrService *services.QRService) *QRHandler {
+	return &QRHandler{
+		qrService: qrService,
+	}
+}
+
+// Generate QR code endpoint
+// @Summary Generate Payment QR Code
+// @Description Generate a QR code for receiving points. Without an amount the code is static and only carries the LBK code; with an amount it is signed and expires
+// @Tags QR
+// @Accept json
+// @Produce png
+// @Produce image/svg+xml
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "png (default), svg or json"
+// @Param size query int false "PNG size in pixels (128-1024, default 256)"
+// @Param qr body models.QRCodeRequest false "Dynamic code details"
+// @Success 200 {object} models.QRPayloadResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /qr [post]
+func (h *QRHandler) GenerateQR(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.QRCodeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+		}
+	}
+
+	response, err := h.qrService.CreatePayload(userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		case "reference requires an amount", "unknown currency":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	switch c.Query("format", "png") {
+	case "json":
+		return c.JSON(response)
+	case "svg":
+		image, err := utils.RenderQRSVG(response.Payload)
+		if err != nil {
+			return c.Status(500).JSON(models.ErrorResponse{Error: "failed to render qr code"})
+		}
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+		return c.Send(image)
+	case "png":
+		size := c.QueryInt("size", 256)
+		if size < 128 || size > 1024 {
+			return c.Status(400).JSON(models.ErrorResponse{Error: "size must be between 128 and 1024"})
+		}
+		image, err := utils.RenderQRPNG(response.Payload, size)
+		if err != nil {
+			return c.Status(500).JSON(models.ErrorResponse{Error: "failed to render qr code"})
+		}
+		c.Set(fiber.HeaderContentType, "image/png")
+		return c.Send(image)
+	default:
+		return c.Status(400).JSON(models.ErrorResponse{Error: "format must be png, svg or json"})
+	}
+}
+
+// Parse QR code endpoint
+// @Summary Parse Payment QR Code
+// @Description Validate a scanned QR payload and return the pre-filled transfer to confirm with POST /points/transfer
+// @Tags QR
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param qr body models.ParseQRRequest true "Scanned payload"
+// @Success 200 {object} models.QRParseResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /qr/parse [post]
+func (h *QRHandler) ParseQR(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.ParseQRRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Payload == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "payload is required"})
+	}
+
+	response, err := h.qrService.ParsePayload(userID, req.Payload)
+	if err != nil {
+		switch err.Error() {
+		case "invalid qr payload", "invalid qr signature", "cannot transfer points to yourself":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		case "recipient user not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		case "qr code expired":
+			return c.Status(410).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.JSON(response)
+}
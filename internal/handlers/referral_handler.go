@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReferralHandler struct {
+	referralService *services.ReferralService
+}
+
+func NewReferralHandler(referralService *services.ReferralService) *ReferralHandler {
+	return &ReferralHandler{
+		referralService: referralService,
+	}
+}
+
+// Get referrals endpoint
+// @Summary Get Referrals
+// @Description Get the authenticated user's referral code and the status of everyone who signed up with it
+// @Tags Referrals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ReferralsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /referrals [get]
+func (h *ReferralHandler) GetReferrals(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.referralService.GetReferrals(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
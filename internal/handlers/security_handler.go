@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SecurityHandler struct {
+	loginGuard  *services.LoginGuard
+	userService *services.UserService
+}
+
+func NewSecurityHandler(loginGuard *services.LoginGuard, userService *services.UserService) *SecurityHandler {
+	return &SecurityHandler{
+		loginGuard:  loginGuard,
+		userService: userService,
+	}
+}
+
+// List security events endpoint
+// @Summary List Security Events
+// @Description Failed logins, lockouts and unlocks, newest first, optionally filtered by type and email (admin only)
+// @Tags Security
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Event type"
+// @Param email query string false "Email address"
+// @Success 200 {object} models.SecurityEventListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/security-events [get]
+func (h *SecurityHandler) ListSecurityEvents(c *fiber.Ctx) error {
+	response, err := h.loginGuard.Events(c.Query("type"), c.Query("email"))
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Unlock user endpoint
+// @Summary Unlock User Login
+// @Description Lift a login lockout on a user's account (admin only)
+// @Tags Security
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/unlock [post]
+func (h *SecurityHandler) UnlockUser(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid user id"})
+	}
+
+	user, err := h.userService.GetUserByID(uint(userID))
+	if err != nil {
+		if err.Error() == "user not found" {
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := h.loginGuard.Unlock(user.ID, user.Email, "unlocked by admin"); err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(user)
+}
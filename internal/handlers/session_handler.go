@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"fiber-api/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// List sessions endpoint
+// @Summary List Sessions
+// @Description Devices where the authenticated user is logged in, most recently used first. The session of the token making the request has current set
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SessionListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/sessions [get]
+func (h *SessionHandler) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID, _ := c.Locals("sessionID").(uint)
+
+	response, err := h.sessionService.List(userID, sessionID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Revoke session endpoint
+// @Summary Revoke Session
+// @Description Sign out one of the authenticated user's sessions; its token is rejected from then on. Revoking the current session logs out
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} models.MessageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	sessionID, err := c.ParamsInt("id")
+	if err != nil || sessionID < 1 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid session id"})
+	}
+
+	if err := h.sessionService.Revoke(userID, uint(sessionID), c.IP()); err != nil {
+		if err.Error() == "session not found" {
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(models.MessageResponse{Message: "Session revoked"})
+}
+
+// startSession records a login of user from the requesting device and returns
+// its token. The device label comes from the request body or, failing that,
+// the X-Device-Name header.
+func startSession(c *fiber.Ctx, sessionService *services.SessionService, user *models.User, deviceName string, jwtSecret []byte) (string, error) {
+	if deviceName == "" {
+		deviceName = c.Get("X-Device-Name")
+	}
+
+	session, err := sessionService.Start(user.ID, deviceName, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return "", err
+	}
+	return utils.GenerateToken(user.ID, user.Email, user.Role, user.TokenVersion, session.ID, jwtSecret)
+}
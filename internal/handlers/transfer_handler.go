@@ -3,6 +3,7 @@ package handlers
 import (
 	"fiber-api/internal/models"
 	"fiber-api/internal/services"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,16 +20,19 @@ func NewTransferHandler(transferService *services.TransferService) *TransferHand
 
 // Transfer points endpoint
 // @Summary Transfer Points
-// @Description Transfer points from authenticated user to another user
+// @Description Transfer points from authenticated user to another user, addressed by LBK code, verified phone number or verified email. Recipients addressed by phone or email are masked in the response. Transfers of at least TRANSFER_PIN_THRESHOLD points need the transaction PIN. Also accepts a personal API key with the transfer scope, up to its transfer limit
 // @Tags Transfer
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security UserAPIKey
 // @Param transfer body models.TransferRequest true "Transfer details"
 // @Success 200 {object} models.TransferResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /points/transfer [post]
 func (h *TransferHandler) TransferPoints(c *fiber.Ctx) error {
@@ -39,22 +43,29 @@ func (h *TransferHandler) TransferPoints(c *fiber.Ctx) error {
 		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
 	}
 
+	// Transfers made with a personal API key count against its limit
+	if apiKeyID, ok := c.Locals("apiKeyID").(uint); ok {
+		req.APIKeyID = &apiKeyID
+	}
+
 	// Basic validation
-	if req.ToLBKCode == "" || req.Amount == 0 {
-		return c.Status(400).JSON(models.ErrorResponse{Error: "to_lbk_code and amount are required"})
+	if (req.ToLBKCode == "" && req.ToPhone == "" && req.ToEmail == "") || req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "to_lbk_code, to_phone or to_email and amount are required"})
 	}
 
 	response, err := h.transferService.TransferPoints(userID, req)
 	if err != nil {
 		switch err.Error() {
-		case "insufficient points":
+		case "insufficient points", "unknown currency", "invalid phone number", "exactly one of to_lbk_code, to_phone or to_email is required":
 			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
 		case "recipient user not found":
 			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
 		case "cannot transfer points to yourself":
 			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		case "api key transfer limit exceeded", "api key cannot transfer":
+			return c.Status(403).JSON(models.ErrorResponse{Error: err.Error()})
 		default:
-			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+			return paymentRequestError(c, err)
 		}
 	}
 
@@ -63,19 +74,22 @@ func (h *TransferHandler) TransferPoints(c *fiber.Ctx) error {
 
 // Get transfer history endpoint
 // @Summary Get Transfer History
-// @Description Get transfer history for authenticated user (both sent and received transfers)
+// @Description Get transfer history for authenticated user (both sent and received transfers). Also accepts a personal API key with the history:read scope
 // @Tags Transfer
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security UserAPIKey
+// @Param currency query string false "Only transfers in this currency"
 // @Success 200 {object} models.TransferHistoryResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /points/history [get]
 func (h *TransferHandler) GetTransferHistory(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
 
-	response, err := h.transferService.GetTransferHistory(userID)
+	response, err := h.transferService.GetTransferHistory(userID, strings.ToUpper(c.Query("currency")))
 	if err != nil {
 		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
 	}
@@ -8,12 +8,14 @@ import (
 )
 
 type UserHandler struct {
-	userService *services.UserService
+	userService   *services.UserService
+	walletService *services.WalletService
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
+func NewUserHandler(userService *services.UserService, walletService *services.WalletService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:   userService,
+		walletService: walletService,
 	}
 }
 
@@ -45,13 +47,17 @@ func (h *UserHandler) GetMe(c *fiber.Ctx) error {
 
 // Get point balance endpoint
 // @Summary Get Point Balance
-// @Description Get authenticated user's point balance
+// @Description Get authenticated user's point balance in one currency. Also accepts a personal API key with the balance:read scope
 // @Tags User
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security UserAPIKey
+// @Param currency query string false "Currency code (default PTS)"
 // @Success 200 {object} models.PointBalanceResponse
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /points/balance [get]
@@ -66,9 +72,19 @@ func (h *UserHandler) GetPointBalance(c *fiber.Ctx) error {
 		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
 	}
 
+	currency := queryCurrency(c)
+	balance, err := h.walletService.GetBalance(userID, currency)
+	if err != nil {
+		if err.Error() == "unknown currency" {
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
 	response := models.PointBalanceResponse{
 		LBKCode:      user.LBKCode,
-		PointBalance: user.PointBalance,
+		Currency:     currency,
+		PointBalance: balance,
 		FirstName:    user.FirstName,
 		LastName:     user.LastName,
 	}
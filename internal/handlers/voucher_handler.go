@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VoucherHandler struct {
+	voucherService *services.VoucherService
+}
+
+func NewVoucherHandler(voucherService *services.VoucherService) *VoucherHandler {
+	return &VoucherHandler{
+		voucherService: voucherService,
+	}
+}
+
+// Create voucher endpoint
+// @Summary Buy Voucher
+// @Description Lock points from the authenticated user's wallet behind a gift voucher code. The code is only returned once; unredeemed vouchers are refunded when they expire. Vouchers of at least TRANSFER_PIN_THRESHOLD points need the transaction PIN
+// @Tags Vouchers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param voucher body models.CreateVoucherRequest true "Voucher details"
+// @Success 201 {object} models.VoucherCreatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 423 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /vouchers [post]
+func (h *VoucherHandler) CreateVoucher(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.CreateVoucherRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "amount is required"})
+	}
+
+	created, err := h.voucherService.Create(userID, req)
+	if err != nil {
+		return voucherError(c, err)
+	}
+
+	return c.Status(201).JSON(created)
+}
+
+// Get vouchers endpoint
+// @Summary Get Vouchers
+// @Description List the vouchers the authenticated user bought
+// @Tags Vouchers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.VoucherListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /vouchers [get]
+func (h *VoucherHandler) GetVouchers(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.voucherService.List(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Redeem voucher endpoint
+// @Summary Redeem Voucher
+// @Description Credit a voucher's value to the authenticated user. Dashes, spaces and case in the code are ignored
+// @Tags Vouchers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param voucher body models.RedeemVoucherRequest true "Voucher code"
+// @Success 200 {object} models.Voucher
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /vouchers/redeem [post]
+func (h *VoucherHandler) RedeemVoucher(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req models.RedeemVoucherRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Code == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "code is required"})
+	}
+
+	voucher, err := h.voucherService.Redeem(userID, req.Code)
+	if err != nil {
+		return voucherError(c, err)
+	}
+
+	return c.JSON(voucher)
+}
+
+// List all vouchers endpoint
+// @Summary List All Vouchers
+// @Description List vouchers of all issuers, optionally filtered by status (admin only)
+// @Tags Vouchers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Voucher status"
+// @Success 200 {object} models.VoucherListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/vouchers [get]
+func (h *VoucherHandler) ListAllVouchers(c *fiber.Ctx) error {
+	response, err := h.voucherService.ListAll(c.Query("status"))
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Issue vouchers endpoint
+// @Summary Issue Vouchers
+// @Description Print a batch of point vouchers paid from the campaign account. Expired vouchers return their value to the account (admin only)
+// @Tags Vouchers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param vouchers body models.IssueVouchersRequest true "Batch details"
+// @Success 201 {object} models.VoucherBatchResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/vouchers [post]
+func (h *VoucherHandler) IssueVouchers(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	var req models.IssueVouchersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "amount is required"})
+	}
+
+	response, err := h.voucherService.Issue(adminID, req)
+	if err != nil {
+		return voucherError(c, err)
+	}
+
+	return c.Status(201).JSON(response)
+}
+
+// voucherError maps voucher errors to HTTP responses
+func voucherError(c *fiber.Ctx, err error) error {
+	switch msg := err.Error(); {
+	case msg == "voucher not found":
+		return c.Status(404).JSON(models.ErrorResponse{Error: msg})
+	case msg == "insufficient points", msg == "unknown currency", msg == "insufficient campaign funds",
+		msg == "count must be between 1 and 100", msg == "amount too large for the batch", strings.HasPrefix(msg, "expires_in_days"):
+		return c.Status(400).JSON(models.ErrorResponse{Error: msg})
+	case msg == "pin required", msg == "pin not set", msg == "invalid pin":
+		return c.Status(403).JSON(models.ErrorResponse{Error: msg})
+	case msg == "voucher already redeemed":
+		return c.Status(409).JSON(models.ErrorResponse{Error: msg})
+	case msg == "voucher expired":
+		return c.Status(410).JSON(models.ErrorResponse{Error: msg})
+	case msg == "pin locked":
+		return c.Status(423).JSON(models.ErrorResponse{Error: msg})
+	default:
+		return c.Status(500).JSON(models.ErrorResponse{Error: msg})
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WalletHandler struct {
+	walletService *services.WalletService
+}
+
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{
+		walletService: walletService,
+	}
+}
+
+// Get wallets endpoint
+// @Summary Get Wallets
+// @Description Get the authenticated user's balance in every currency they hold. Also accepts a personal API key with the balance:read scope
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security UserAPIKey
+// @Success 200 {object} models.WalletsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /wallets [get]
+func (h *WalletHandler) GetWallets(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	response, err := h.walletService.GetWallets(userID)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Get currencies endpoint
+// @Summary Get Currencies
+// @Description List the currencies points can currently be held and transferred in
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CurrencyListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /currencies [get]
+func (h *WalletHandler) GetCurrencies(c *fiber.Ctx) error {
+	response, err := h.walletService.ListCurrencies(false)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// List currencies endpoint
+// @Summary List Currencies
+// @Description List every currency including inactive ones (admin only)
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CurrencyListResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/currencies [get]
+func (h *WalletHandler) ListCurrencies(c *fiber.Ctx) error {
+	response, err := h.walletService.ListCurrencies(true)
+	if err != nil {
+		return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// Create currency endpoint
+// @Summary Create Currency
+// @Description Add a point program users can hold a wallet in (admin only)
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param currency body models.CreateCurrencyRequest true "Currency details"
+// @Success 201 {object} models.Currency
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/currencies [post]
+func (h *WalletHandler) CreateCurrency(c *fiber.Ctx) error {
+	var req models.CreateCurrencyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.Code == "" || req.Name == "" {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "code and name are required"})
+	}
+
+	currency, err := h.walletService.CreateCurrency(req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid currency code", "currency already exists":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.Status(201).JSON(currency)
+}
+
+// Update currency endpoint
+// @Summary Update Currency
+// @Description Rename a currency or stop transfers in it (admin only)
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Currency code"
+// @Param currency body models.UpdateCurrencyRequest true "Fields to update"
+// @Success 200 {object} models.Currency
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/currencies/{code} [patch]
+func (h *WalletHandler) UpdateCurrency(c *fiber.Ctx) error {
+	var req models.UpdateCurrencyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	currency, err := h.walletService.UpdateCurrency(c.Params("code"), req)
+	if err != nil {
+		switch err.Error() {
+		case "currency not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		case "the default currency cannot be deactivated":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.JSON(currency)
+}
+
+// Issue points endpoint
+// @Summary Issue Points
+// @Description Credit newly issued points of a currency to a user (admin only)
+// @Tags Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Currency code"
+// @Param issue body models.IssuePointsRequest true "Recipient and amount"
+// @Success 201 {object} models.Transfer
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/currencies/{code}/issue [post]
+func (h *WalletHandler) IssuePoints(c *fiber.Ctx) error {
+	var req models.IssuePointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "Invalid request body"})
+	}
+
+	// Basic validation
+	if req.ToLBKCode == "" || req.Amount == 0 {
+		return c.Status(400).JSON(models.ErrorResponse{Error: "to_lbk_code and amount are required"})
+	}
+
+	entry, err := h.walletService.Issue(c.Params("code"), req)
+	if err != nil {
+		switch err.Error() {
+		case "unknown currency":
+			return c.Status(400).JSON(models.ErrorResponse{Error: err.Error()})
+		case "recipient user not found":
+			return c.Status(404).JSON(models.ErrorResponse{Error: err.Error()})
+		default:
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	return c.Status(201).JSON(entry)
+}
+
+// queryCurrency reads the optional currency query parameter, defaulting to PTS
+func queryCurrency(c *fiber.Ctx) string {
+	return services.NormalizeCurrency(c.Query("currency"))
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Job is a unit of background work run by the Scheduler
+type Job func(now time.Time) error
+
+type Scheduler struct {
+	daily    []dailyJob
+	interval []intervalJob
+}
+
+type dailyJob struct {
+	name string
+	hour int
+	run  Job
+}
+
+type intervalJob struct {
+	name  string
+	every time.Duration
+	run   Job
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Daily registers a job that runs once a day at the given local hour
+func (s *Scheduler) Daily(name string, hour int, run Job) {
+	s.daily = append(s.daily, dailyJob{name: name, hour: hour, run: run})
+}
+
+// Every registers a job that runs repeatedly with the given pause in between
+func (s *Scheduler) Every(name string, every time.Duration, run Job) {
+	s.interval = append(s.interval, intervalJob{name: name, every: every, run: run})
+}
+
+// Start launches every registered job in its own goroutine
+func (s *Scheduler) Start() {
+	for _, job := range s.daily {
+		go job.loop()
+	}
+	for _, job := range s.interval {
+		go job.loop()
+	}
+}
+
+func (j dailyJob) loop() {
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), j.hour, 0, 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		time.Sleep(time.Until(next))
+
+		log.Printf("Running job %s", j.name)
+		if err := j.run(time.Now()); err != nil {
+			log.Printf("Job %s failed: %v", j.name, err)
+		}
+	}
+}
+
+func (j intervalJob) loop() {
+	for {
+		time.Sleep(j.every)
+
+		if err := j.run(time.Now()); err != nil {
+			log.Printf("Job %s failed: %v", j.name, err)
+		}
+	}
+}
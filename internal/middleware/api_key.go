@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyOrJWTMiddleware authenticates users by a personal API key in the
+// X-API-Key header, which must grant scope, or else by jwtMiddleware. Routes
+// without it accept JWTs only.
+func APIKeyOrJWTMiddleware(jwtMiddleware fiber.Handler, apiKeyService *services.APIKeyService, scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("X-API-Key")
+		if key == "" {
+			return jwtMiddleware(c)
+		}
+
+		apiKey, user, err := apiKeyService.Authenticate(key, c.IP())
+		if err != nil {
+			return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid API key"})
+		}
+		if !services.HasScope(apiKey, scope) {
+			return c.Status(403).JSON(models.ErrorResponse{Error: "API key lacks the " + scope + " scope"})
+		}
+
+		// Store user info in context; keys never carry the admin role
+		c.Locals("userID", user.ID)
+		c.Locals("email", user.Email)
+		c.Locals("role", "user")
+		c.Locals("apiKeyID", apiKey.ID)
+		return c.Next()
+	}
+}
@@ -2,13 +2,17 @@ package middleware
 
 import (
 	"fiber-api/internal/models"
+	"fiber-api/internal/services"
 	"fiber-api/internal/utils"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func JWTMiddleware(jwtSecret []byte) fiber.Handler {
+// JWTMiddleware authenticates users by their bearer token. Tokens issued
+// before the user's last password reset, and tokens of revoked sessions, are
+// refused.
+func JWTMiddleware(jwtSecret []byte, userService *services.UserService, sessionService *services.SessionService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -25,13 +29,68 @@ func JWTMiddleware(jwtSecret []byte) fiber.Handler {
 
 		// Parse and validate the token
 		claims, err := utils.ParseToken(tokenString, jwtSecret)
-		if err != nil {
+		if err != nil || claims.Purpose != "" {
 			return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid token"})
 		}
 
+		version, err := userService.TokenVersion(claims.UserID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid token"})
+			}
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		if claims.Version != version {
+			return c.Status(401).JSON(models.ErrorResponse{Error: "Token has been revoked"})
+		}
+
+		// Tokens issued before sessions were tracked carry none. They expire
+		// within a day, and changing the password replaces them with a session.
+		if claims.SessionID != 0 {
+			if err := sessionService.Touch(claims.UserID, claims.SessionID, c.IP()); err != nil {
+				if err.Error() == "session revoked" {
+					return c.Status(401).JSON(models.ErrorResponse{Error: "Session has been revoked"})
+				}
+				return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+			}
+		}
+
 		// Store user info in context
 		c.Locals("userID", claims.UserID)
 		c.Locals("email", claims.Email)
+		c.Locals("role", claims.Role)
+		c.Locals("sessionID", claims.SessionID)
+		return c.Next()
+	}
+}
+
+// AdminMiddleware only lets through users whose token carries the admin role.
+// It must run after JWTMiddleware.
+func AdminMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if role, _ := c.Locals("role").(string); role != "admin" {
+			return c.Status(403).JSON(models.ErrorResponse{Error: "Admin access required"})
+		}
+		return c.Next()
+	}
+}
+
+// VerifiedEmailMiddleware refuses users who have not verified their email
+// address yet. When required is false every user is let through. It must run
+// after JWTMiddleware.
+func VerifiedEmailMiddleware(userService *services.UserService, required bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !required {
+			return c.Next()
+		}
+
+		user, err := userService.GetUserByID(c.Locals("userID").(uint))
+		if err != nil {
+			return c.Status(500).JSON(models.ErrorResponse{Error: err.Error()})
+		}
+		if !user.EmailVerified {
+			return c.Status(403).JSON(models.ErrorResponse{Error: "Email address not verified"})
+		}
 		return c.Next()
 	}
 }
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"fiber-api/internal/models"
+	"fiber-api/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MerchantMiddleware authenticates merchant servers by the X-API-Key header
+func MerchantMiddleware(merchantService *services.MerchantService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("X-API-Key")
+		if key == "" {
+			return c.Status(401).JSON(models.ErrorResponse{Error: "Missing API key"})
+		}
+
+		merchant, err := merchantService.AuthenticateAPIKey(key)
+		if err != nil {
+			return c.Status(401).JSON(models.ErrorResponse{Error: "Invalid API key"})
+		}
+
+		// Store merchant info in context
+		c.Locals("merchantID", merchant.ID)
+		return c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// UserAPIKey lets a user's own scripts and bots call a few endpoints without
+// the password. Only a hash of the key is stored; Prefix identifies the key
+// without revealing it.
+type UserAPIKey struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	Name          string     `json:"name" gorm:"not null"`
+	Prefix        string     `json:"prefix" gorm:"not null;uniqueIndex"`
+	KeyHash       string     `json:"-" gorm:"not null"`
+	Scopes        []string   `json:"scopes" gorm:"serializer:json"` // balance:read, history:read, transfer
+	TransferLimit uint       `json:"transfer_limit"`                // Points the key may send per 24 hours, with the transfer scope
+	ExpiresAt     *time.Time `json:"expires_at"`                    // nil for a key that does not expire
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	LastUsedIP    string     `json:"last_used_ip"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
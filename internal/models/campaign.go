@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Campaign is an operator-defined reward rule paid out from the campaign account
+type Campaign struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	Name         string     `json:"name" gorm:"not null"`
+	Type         string     `json:"type" gorm:"not null;index"` // signup, referral, birthday, first_transfer
+	RewardAmount uint       `json:"reward_amount" gorm:"not null"`
+	Budget       uint       `json:"budget" gorm:"default:0"`       // Total points the campaign may pay out, 0 means unlimited
+	Spent        uint       `json:"spent" gorm:"default:0"`        // Points paid out so far
+	PerUserCap   uint       `json:"per_user_cap" gorm:"default:1"` // Rewards per user (per year for birthday campaigns)
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Active       bool       `json:"active" gorm:"default:true"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CampaignReward records a single payout to a user
+type CampaignReward struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CampaignID uint      `json:"campaign_id" gorm:"not null;index"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	TransferID uint      `json:"transfer_id" gorm:"not null"`
+	Amount     uint      `json:"amount" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CampaignAccount holds the points available to all campaigns. There is a
+// single row; operators top it up and every reward is debited from it.
+type CampaignAccount struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Balance   uint      `json:"balance" gorm:"default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CampaignFunding records a top-up of the campaign account
+type CampaignFunding struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Amount    uint      `json:"amount" gorm:"not null"`
+	FundedBy  *uint     `json:"funded_by"` // nil for the startup seed
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// CatalogItem is a reward users can redeem points for
+type CatalogItem struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	Name         string    `json:"name" gorm:"not null"`
+	Description  string    `json:"description"`
+	PointPrice   uint      `json:"point_price" gorm:"not null"`
+	Stock        uint      `json:"stock" gorm:"default:0"`          // Units left to redeem
+	PerUserLimit uint      `json:"per_user_limit" gorm:"default:0"` // Units one user may hold in open or fulfilled orders, 0 means unlimited
+	Active       bool      `json:"active" gorm:"default:true"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RedemptionOrder is a user's redemption of a catalog item
+type RedemptionOrder struct {
+	ID          uint        `json:"id" gorm:"primarykey"`
+	UserID      uint        `json:"user_id" gorm:"not null;index"`
+	ItemID      uint        `json:"item_id" gorm:"not null;index"`
+	Item        CatalogItem `json:"item" gorm:"foreignKey:ItemID"`
+	Quantity    uint        `json:"quantity" gorm:"not null"`
+	PointsSpent uint        `json:"points_spent" gorm:"not null"`
+	Status      string      `json:"status" gorm:"default:'pending'"` // pending, shipped, fulfilled, cancelled
+	TransferID  uint        `json:"transfer_id"`                     // History entry of the debit
+	CancelledAt *time.Time  `json:"cancelled_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
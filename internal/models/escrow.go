@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Escrow holds points taken from the sender until they confirm delivery. The
+// points belong to neither wallet while held; they go to the recipient on
+// release and back to the sender on refund.
+type Escrow struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	TransferID     uint       `json:"transfer_id" gorm:"uniqueIndex;not null"`
+	SenderID       uint       `json:"sender_id" gorm:"not null;index"`
+	RecipientID    uint       `json:"recipient_id" gorm:"not null;index"`
+	Amount         uint       `json:"amount" gorm:"not null"`
+	Currency       string     `json:"currency" gorm:"not null"`
+	Status         string     `json:"status" gorm:"default:'held';index"` // held, disputed, released, refunded
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null;index"`   // Held escrows are refunded after this
+	DisputedBy     *uint      `json:"disputed_by"`
+	DisputeReason  string     `json:"dispute_reason"`
+	ResolvedBy     *uint      `json:"resolved_by"` // Admin who settled a dispute
+	ResolutionNote string     `json:"resolution_note"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate converts FromCurrency into ToCurrency from EffectiveFrom until
+// a newer rate for the same pair takes effect. Rates are fixed-point with six
+// decimals so conversions never go through floating point.
+type ExchangeRate struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	FromCurrency  string    `json:"from_currency" gorm:"not null;index:idx_exchange_rates_pair"`
+	ToCurrency    string    `json:"to_currency" gorm:"not null;index:idx_exchange_rates_pair"`
+	Rate          string    `json:"rate" gorm:"not null"` // Decimal form of RateMicros, e.g. "1.25"
+	RateMicros    uint64    `json:"-" gorm:"not null"`    // Units of ToCurrency per FromCurrency, times 1,000,000
+	EffectiveFrom time.Time `json:"effective_from" gorm:"not null;index"`
+	CreatedBy     *uint     `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Conversion is a quote for exchanging points between two of a user's wallets
+// and, once executed, the ledger record of the exchange. The converted amount
+// is always rounded down to whole points; the dropped fraction is kept in
+// RemainderMicros.
+type Conversion struct {
+	ID               uint       `json:"-" gorm:"primarykey"`
+	Code             string     `json:"id" gorm:"uniqueIndex;not null"` // Public identifier, "cq_..."
+	UserID           uint       `json:"user_id" gorm:"not null;index"`
+	FromCurrency     string     `json:"from_currency" gorm:"not null"`
+	ToCurrency       string     `json:"to_currency" gorm:"not null"`
+	FromAmount       uint       `json:"from_amount" gorm:"not null"`
+	ToAmount         uint       `json:"to_amount" gorm:"not null"`
+	RateID           uint       `json:"rate_id" gorm:"not null"`
+	Rate             string     `json:"rate" gorm:"not null"`
+	Rounding         string     `json:"rounding" gorm:"not null"`         // Always "down"
+	RemainderMicros  uint64     `json:"remainder_micros" gorm:"not null"` // Fraction of a ToCurrency point lost to rounding, times 1,000,000
+	Status           string     `json:"status" gorm:"default:'quoted'"`   // quoted, executed, expired
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null"`
+	DebitTransferID  *uint      `json:"debit_transfer_id"`
+	CreditTransferID *uint      `json:"credit_transfer_id"`
+	ExecutedAt       *time.Time `json:"executed_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Merchant is a shop that accepts points. Merchants are not users: they
+// authenticate with API keys and their balance only grows through payments.
+type Merchant struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name" gorm:"not null"`
+	Email     string    `json:"email" gorm:"unique;not null"`
+	Balance   uint      `json:"balance" gorm:"default:0"`       // Points settled to the merchant
+	Status    string    `json:"status" gorm:"default:'active'"` // active, suspended
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MerchantAPIKey authenticates a merchant's server. Only a hash of the key is
+// stored; Prefix identifies the key without revealing it.
+type MerchantAPIKey struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	MerchantID uint       `json:"merchant_id" gorm:"not null;index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"not null;uniqueIndex"`
+	KeyHash    string     `json:"-" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PaymentIntent is a merchant's request for points that a customer confirms
+type PaymentIntent struct {
+	ID          uint       `json:"-" gorm:"primarykey"`
+	Code        string     `json:"id" gorm:"not null;uniqueIndex"` // Public, unguessable identifier
+	MerchantID  uint       `json:"merchant_id" gorm:"not null;index"`
+	Merchant    Merchant   `json:"-" gorm:"foreignKey:MerchantID"`
+	Amount      uint       `json:"amount" gorm:"not null"`
+	Reference   string     `json:"reference"` // Merchant's own order reference
+	Description string     `json:"description"`
+	Status      string     `json:"status" gorm:"default:'requires_confirmation'"` // requires_confirmation, succeeded, cancelled, expired
+	CustomerID  *uint      `json:"customer_id"`
+	TransferID  *uint      `json:"transfer_id"` // History entry of the customer's debit
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
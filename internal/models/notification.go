@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification is an in-app message shown to a user, e.g. a payment reminder
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Type      string     `json:"type" gorm:"not null"` // payment_request, payment_reminder
+	Message   string     `json:"message" gorm:"not null"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// PaymentRequest asks several users to pay their share of a bill to the
+// requester. It closes by itself once every share is paid.
+type PaymentRequest struct {
+	ID             uint                  `json:"id" gorm:"primarykey"`
+	RequesterID    uint                  `json:"requester_id" gorm:"not null;index"`
+	Title          string                `json:"title" gorm:"not null"`
+	TotalAmount    uint                  `json:"total_amount" gorm:"not null"`
+	Currency       string                `json:"currency" gorm:"not null"`
+	SplitMode      string                `json:"split_mode" gorm:"not null"`   // even, custom
+	RequesterShare uint                  `json:"requester_share"`              // Part of the total the requester covers themselves
+	Status         string                `json:"status" gorm:"default:'open'"` // open, paid, cancelled
+	Shares         []PaymentRequestShare `json:"shares" gorm:"foreignKey:RequestID"`
+	ClosedAt       *time.Time            `json:"closed_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// PaymentRequestShare is what one participant owes on a payment request
+type PaymentRequestShare struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	RequestID     uint       `json:"request_id" gorm:"not null;uniqueIndex:idx_payment_request_shares_user"`
+	UserID        uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_payment_request_shares_user;index"`
+	LBKCode       string     `json:"lbk_code" gorm:"not null"`
+	Amount        uint       `json:"amount" gorm:"not null"`
+	Status        string     `json:"status" gorm:"default:'pending'"` // pending, paid
+	TransferID    *uint      `json:"transfer_id"`
+	PaidAt        *time.Time `json:"paid_at"`
+	RemindedAt    *time.Time `json:"reminded_at"`
+	ReminderCount uint       `json:"reminder_count" gorm:"default:0"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PointLot is a batch of points granted to a user at one time. Lots are
+// consumed oldest-first and whatever remains is forfeited once ExpiresAt passes.
+type PointLot struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Currency  string     `json:"currency" gorm:"default:'PTS'"`
+	Amount    uint       `json:"amount" gorm:"not null"`    // Points originally granted
+	Remaining uint       `json:"remaining" gorm:"not null"` // Points not yet spent or expired
+	Source    string     `json:"source" gorm:"not null"`    // registration, transfer, migration
+	GrantedAt time.Time  `json:"granted_at" gorm:"not null"`
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"` // nil means the lot never expires
+	ExpiredAt *time.Time `json:"expired_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
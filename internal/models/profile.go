@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ProfileChange records one modification of a user's profile so support can
+// see what changed and when. Password changes are recorded without values.
+type ProfileChange struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Field     string    `json:"field" gorm:"not null"`           // first_name, last_name, phone_number, dob, password
+	OldValue  string    `json:"old_value" gorm:"serializer:pii"` // Encrypted at rest, like the profile itself
+	NewValue  string    `json:"new_value" gorm:"serializer:pii"`
+	Source    string    `json:"source"` // profile, phone, password_change, password_reset
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
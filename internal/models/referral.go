@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Referral links a new user to the user whose referral code they signed up with
+type Referral struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	ReferrerID   uint       `json:"referrer_id" gorm:"not null;index"`
+	RefereeID    uint       `json:"referee_id" gorm:"not null;uniqueIndex"`
+	Referee      User       `json:"-" gorm:"foreignKey:RefereeID"`
+	Status       string     `json:"status" gorm:"default:'pending'"` // pending, qualified, rewarded, rejected
+	RejectReason string     `json:"-"`                               // same_device, same_email_domain, same_phone
+	QualifiedAt  *time.Time `json:"qualified_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
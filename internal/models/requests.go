@@ -1,22 +1,296 @@
 package models
 
+import (
+	"time"
+)
+
 // Request structures
 type RegisterRequest struct {
-	Email       string `json:"email" validate:"required,email"`
-	Password    string `json:"password" validate:"required,min=6"`
-	FirstName   string `json:"first_name" validate:"required"`
-	LastName    string `json:"last_name" validate:"required"`
-	PhoneNumber string `json:"phone_number"`
-	DOB         string `json:"dob"` // Format: "2006-01-02"
+	Email        string `json:"email" validate:"required,email"`
+	Password     string `json:"password" validate:"required"` // Checked against the password policy
+	FirstName    string `json:"first_name" validate:"required"`
+	LastName     string `json:"last_name" validate:"required"`
+	PhoneNumber  string `json:"phone_number"`  // Normalized to E.164; verify it with /me/phone/code
+	DOB          string `json:"dob"`           // Format: "2006-01-02"
+	ReferralCode string `json:"referral_code"` // Optional code of the user who invited them
+	DeviceID     string `json:"device_id"`     // Optional client device identifier
+	DeviceName   string `json:"device_name"`   // Optional label shown in /me/sessions, e.g. "Pixel 8"
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required"`
+	DeviceName string `json:"device_name"` // Optional label shown in /me/sessions, e.g. "Pixel 8"
 }
 
+// TransferRequest addresses the recipient by exactly one of LBK code,
+// verified phone number or verified email
 type TransferRequest struct {
+	ToLBKCode string `json:"to_lbk_code"`
+	ToPhone   string `json:"to_phone,omitempty"` // Any format accepted by PUT /me/phone
+	ToEmail   string `json:"to_email,omitempty"`
+	Amount    uint   `json:"amount" validate:"required,min=1"`
+	Currency  string `json:"currency"` // Defaults to PTS
+	Message   string `json:"message"`
+
+	PaymentRequestID *uint  `json:"payment_request_id,omitempty"` // Settles the sender's share of this payment request
+	PIN              string `json:"pin,omitempty"`                // Transaction PIN, required from TRANSFER_PIN_THRESHOLD points
+	APIKeyID         *uint  `json:"-"`                            // Personal API key the request was made with, nil for a JWT
+}
+
+type CreateCampaignRequest struct {
+	Name         string     `json:"name" validate:"required"`
+	Type         string     `json:"type" validate:"required,oneof=signup referral birthday first_transfer"`
+	RewardAmount uint       `json:"reward_amount" validate:"required,min=1"`
+	Budget       uint       `json:"budget"`
+	PerUserCap   uint       `json:"per_user_cap"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+}
+
+type UpdateCampaignRequest struct {
+	Name         *string    `json:"name"`
+	RewardAmount *uint      `json:"reward_amount"`
+	Budget       *uint      `json:"budget"`
+	PerUserCap   *uint      `json:"per_user_cap"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Active       *bool      `json:"active"`
+}
+
+type FundCampaignAccountRequest struct {
+	Amount uint   `json:"amount" validate:"required,min=1"`
+	Note   string `json:"note"`
+}
+
+type RedeemRequest struct {
+	Quantity uint `json:"quantity"` // Defaults to 1, at most 100
+}
+
+type CreateCatalogItemRequest struct {
+	Name         string `json:"name" validate:"required"`
+	Description  string `json:"description"`
+	PointPrice   uint   `json:"point_price" validate:"required,min=1"`
+	Stock        uint   `json:"stock"`
+	PerUserLimit uint   `json:"per_user_limit"`
+}
+
+type UpdateCatalogItemRequest struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	PointPrice   *uint   `json:"point_price"`
+	Stock        *uint   `json:"stock"`
+	PerUserLimit *uint   `json:"per_user_limit"`
+	Active       *bool   `json:"active"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=shipped fulfilled cancelled"` // pending -> shipped -> fulfilled; pending or shipped -> cancelled
+}
+
+type CreateMerchantRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateUserAPIKeyRequest issues a personal API key. The password, and the
+// second factor when 2FA is enabled, confirm it is the user asking.
+type CreateUserAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required"`
+	Scopes        []string `json:"scopes" validate:"required"` // balance:read, history:read, transfer
+	TransferLimit uint     `json:"transfer_limit"`             // Points per 24 hours, required with the transfer scope
+	ExpiresInDays int      `json:"expires_in_days"`            // 0 for a key that does not expire
+	Password      string   `json:"password" validate:"required"`
+	Code          string   `json:"code"`
+	RecoveryCode  string   `json:"recovery_code"`
+}
+
+type CreatePaymentIntentRequest struct {
+	Amount      uint   `json:"amount" validate:"required,min=1"`
+	Reference   string `json:"reference"`
+	Description string `json:"description"`
+}
+
+type QRCodeRequest struct {
+	Amount           uint   `json:"amount"`             // Leave empty for a static code carrying only the LBK code
+	Reference        string `json:"reference"`          // Pre-filled transfer message for dynamic codes
+	ExpiresInMinutes int    `json:"expires_in_minutes"` // Lifetime of a dynamic code, defaults to QR_TTL_MINUTES
+	Currency         string `json:"currency"`           // Currency of the amount, defaults to PTS
+}
+
+type ParseQRRequest struct {
+	Payload string `json:"payload" validate:"required"`
+}
+
+type CreateCurrencyRequest struct {
+	Code string `json:"code" validate:"required"` // 2-10 upper-case letters or digits, e.g. GIFT
+	Name string `json:"name" validate:"required"`
+}
+
+type UpdateCurrencyRequest struct {
+	Name   *string `json:"name"`
+	Active *bool   `json:"active"`
+}
+
+type IssuePointsRequest struct {
 	ToLBKCode string `json:"to_lbk_code" validate:"required"`
 	Amount    uint   `json:"amount" validate:"required,min=1"`
 	Message   string `json:"message"`
 }
+
+type CreateExchangeRateRequest struct {
+	FromCurrency  string     `json:"from_currency" validate:"required"`
+	ToCurrency    string     `json:"to_currency" validate:"required"`
+	Rate          string     `json:"rate" validate:"required"` // Decimal with up to 6 places, e.g. "0.125"
+	EffectiveFrom *time.Time `json:"effective_from"`           // Defaults to now
+}
+
+type ConversionQuoteRequest struct {
+	FromCurrency string `json:"from_currency" validate:"required"`
+	ToCurrency   string `json:"to_currency" validate:"required"`
+	Amount       uint   `json:"amount" validate:"required,min=1"` // Points of FromCurrency to convert
+}
+
+type CreateEscrowRequest struct {
+	ToLBKCode      string `json:"to_lbk_code" validate:"required"`
+	Amount         uint   `json:"amount" validate:"required,min=1"`
+	Currency       string `json:"currency"` // Defaults to PTS
+	Message        string `json:"message"`
+	ExpiresInHours int    `json:"expires_in_hours"` // Refund deadline, defaults to ESCROW_TIMEOUT_HOURS
+	PIN            string `json:"pin,omitempty"`    // Transaction PIN, required from TRANSFER_PIN_THRESHOLD points
+}
+
+type DisputeEscrowRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type ResolveEscrowRequest struct {
+	Resolution string `json:"resolution" validate:"required,oneof=release refund"`
+	Note       string `json:"note"`
+}
+
+type CreatePaymentRequestRequest struct {
+	Title            string                `json:"title" validate:"required"`
+	Currency         string                `json:"currency"`          // Defaults to PTS
+	Split            string                `json:"split"`             // even (default) or custom
+	TotalAmount      uint                  `json:"total_amount"`      // Required for even splits
+	Participants     []string              `json:"participants"`      // LBK codes for even splits
+	IncludeRequester bool                  `json:"include_requester"` // Even splits count the requester as one of the diners
+	Shares           []PaymentShareRequest `json:"shares"`            // Custom splits
+}
+
+type PaymentShareRequest struct {
+	LBKCode string `json:"lbk_code" validate:"required"`
+	Amount  uint   `json:"amount" validate:"required,min=1"`
+}
+
+type CreateVoucherRequest struct {
+	Amount        uint   `json:"amount" validate:"required,min=1"`
+	Currency      string `json:"currency"` // Defaults to PTS
+	Message       string `json:"message"`
+	ExpiresInDays int    `json:"expires_in_days"` // Defaults to VOUCHER_VALIDITY_DAYS
+	PIN           string `json:"pin,omitempty"`   // Transaction PIN, required from TRANSFER_PIN_THRESHOLD points
+}
+
+type IssueVouchersRequest struct {
+	Amount        uint   `json:"amount" validate:"required,min=1"`
+	Count         int    `json:"count"` // Number of vouchers to print, defaults to 1
+	Message       string `json:"message"`
+	ExpiresInDays int    `json:"expires_in_days"` // Defaults to VOUCHER_VALIDITY_DAYS
+}
+
+type RedeemVoucherRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type SetPINRequest struct {
+	Password string `json:"password" validate:"required"`
+	PIN      string `json:"pin" validate:"required,len=6,numeric"`
+}
+
+type ChangePINRequest struct {
+	CurrentPIN string `json:"current_pin" validate:"required"`
+	NewPIN     string `json:"new_pin" validate:"required,len=6,numeric"`
+}
+
+// ResetPINRequest replaces a forgotten PIN using the account password
+type ResetPINRequest struct {
+	Password string `json:"password" validate:"required"`
+	NewPIN   string `json:"new_pin" validate:"required,len=6,numeric"`
+}
+
+type PayShareRequest struct {
+	PIN string `json:"pin"` // Transaction PIN, required from TRANSFER_PIN_THRESHOLD points
+}
+
+type ConfirmPaymentRequest struct {
+	PIN string `json:"pin"` // Transaction PIN, required from TRANSFER_PIN_THRESHOLD points
+}
+
+type EnrollMFARequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFALoginRequest completes a login with either a TOTP code or a recovery code
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	DeviceName   string `json:"device_name"` // Optional label shown in /me/sessions, e.g. "Pixel 8"
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with the token from the reset email
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"` // Checked against the password policy
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ChangePhoneRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required"` // International (+66...) or national format
+}
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// UpdateProfileRequest changes only the fields that are present
+type UpdateProfileRequest struct {
+	FirstName   *string `json:"first_name,omitempty"`
+	LastName    *string `json:"last_name,omitempty"`
+	PhoneNumber *string `json:"phone_number,omitempty"` // Empty to remove; a new number must be verified again
+	DOB         *string `json:"dob,omitempty"`          // Format: "2006-01-02", empty to remove
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"` // Checked against the password policy
+}
+
+// CloseAccountRequest confirms closing the authenticated user's account
+type CloseAccountRequest struct {
+	Password       string `json:"password" validate:"required"`
+	Code           string `json:"code,omitempty"`              // TOTP code, required when 2FA is enabled unless recovery_code is given
+	RecoveryCode   string `json:"recovery_code,omitempty"`     // Replaces code when the authenticator is lost
+	SweepToLBKCode string `json:"sweep_to_lbk_code,omitempty"` // Receives any remaining balance; required unless every wallet is empty
+}
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"time"
+)
+
 // Response structures
 type HelloResponse struct {
 	Message string `json:"message"`
@@ -16,6 +20,7 @@ type ErrorResponse struct {
 
 type PointBalanceResponse struct {
 	LBKCode      string `json:"lbk_code"`
+	Currency     string `json:"currency"`
 	PointBalance uint   `json:"point_balance"`
 	FirstName    string `json:"first_name"`
 	LastName     string `json:"last_name"`
@@ -33,9 +38,11 @@ type TransferResponse struct {
 		LBKCode   string `json:"lbk_code"`
 		FirstName string `json:"first_name"`
 		LastName  string `json:"last_name"`
-	} `json:"to_user"`
-	Amount uint   `json:"amount"`
-	Status string `json:"status"`
+		Alias     string `json:"alias,omitempty"` // Masked phone or email the recipient was addressed by
+	} `json:"to_user"` // Masked when addressed by phone or email
+	Amount   uint   `json:"amount"`
+	Currency string `json:"currency"`
+	Status   string `json:"status"`
 }
 
 type UserSearchResponse struct {
@@ -48,3 +55,228 @@ type TransferHistoryResponse struct {
 	Transfers []Transfer `json:"transfers"`
 	Count     int        `json:"count"`
 }
+
+type ExpiringLot struct {
+	LotID     uint      `json:"lot_id"`
+	Currency  string    `json:"currency"`
+	Remaining uint      `json:"remaining"`
+	Source    string    `json:"source"`
+	GrantedAt time.Time `json:"granted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ExpiringPointsResponse struct {
+	Currency      string        `json:"currency"`
+	Days          int           `json:"days"`
+	TotalExpiring uint          `json:"total_expiring"`
+	Lots          []ExpiringLot `json:"lots"`
+}
+
+type CampaignListResponse struct {
+	Campaigns []Campaign `json:"campaigns"`
+	Count     int        `json:"count"`
+}
+
+type CampaignAccountResponse struct {
+	Balance  uint              `json:"balance"`
+	Fundings []CampaignFunding `json:"fundings"`
+}
+
+type ReferralSummary struct {
+	ID          uint       `json:"id"`
+	RefereeName string     `json:"referee_name"` // First name and last initial only
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	QualifiedAt *time.Time `json:"qualified_at"`
+}
+
+type ReferralsResponse struct {
+	ReferralCode string            `json:"referral_code"`
+	Referrals    []ReferralSummary `json:"referrals"`
+	Count        int               `json:"count"`
+}
+
+type CatalogResponse struct {
+	Items []CatalogItem `json:"items"`
+	Count int           `json:"count"`
+}
+
+type OrderListResponse struct {
+	Orders []RedemptionOrder `json:"orders"`
+	Count  int               `json:"count"`
+}
+
+type MerchantListResponse struct {
+	Merchants []Merchant `json:"merchants"`
+	Count     int        `json:"count"`
+}
+
+// APIKeyCreatedResponse is the only time the full key is ever returned
+type APIKeyCreatedResponse struct {
+	Key    string         `json:"key"`
+	APIKey MerchantAPIKey `json:"api_key"`
+}
+
+// UserAPIKeyCreatedResponse is the only time the full key is ever returned
+type UserAPIKeyCreatedResponse struct {
+	Key    string     `json:"key"`
+	APIKey UserAPIKey `json:"api_key"`
+}
+
+type UserAPIKeyListResponse struct {
+	APIKeys []UserAPIKey `json:"api_keys"`
+	Count   int          `json:"count"`
+}
+
+// PaymentIntentResponse is what a customer sees before confirming a payment
+type PaymentIntentResponse struct {
+	ID           string    `json:"id"`
+	MerchantName string    `json:"merchant_name"`
+	Amount       uint      `json:"amount"`
+	Reference    string    `json:"reference"`
+	Description  string    `json:"description"`
+	Status       string    `json:"status"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type MerchantTransactionsResponse struct {
+	Balance      uint            `json:"balance"`
+	Transactions []PaymentIntent `json:"transactions"`
+	Count        int             `json:"count"`
+}
+
+type QRPayloadResponse struct {
+	Payload   string     `json:"payload"`
+	Type      string     `json:"type"` // static or dynamic
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// QRParseResponse describes a scanned code and the transfer it pre-fills.
+// AmountLocked is set for signed dynamic codes whose amount the payer should not edit.
+type QRParseResponse struct {
+	Type         string             `json:"type"`
+	Recipient    UserSearchResponse `json:"recipient"`
+	Transfer     TransferRequest    `json:"transfer"`
+	AmountLocked bool               `json:"amount_locked"`
+	ExpiresAt    *time.Time         `json:"expires_at,omitempty"`
+}
+
+type WalletBalance struct {
+	Currency string `json:"currency"`
+	Name     string `json:"name"`
+	Balance  uint   `json:"balance"`
+}
+
+type WalletsResponse struct {
+	Wallets []WalletBalance `json:"wallets"`
+	Count   int             `json:"count"`
+}
+
+type CurrencyListResponse struct {
+	Currencies []Currency `json:"currencies"`
+	Count      int        `json:"count"`
+}
+
+type ExchangeRateListResponse struct {
+	Rates []ExchangeRate `json:"rates"`
+	Count int            `json:"count"`
+}
+
+type EscrowListResponse struct {
+	Escrows []Escrow `json:"escrows"`
+	Count   int      `json:"count"`
+}
+
+type PaymentRequestListResponse struct {
+	Requests []PaymentRequest `json:"requests"`
+	Count    int              `json:"count"`
+}
+
+type RemindersResponse struct {
+	Reminded int `json:"reminded"`
+}
+
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int            `json:"unread"`
+	Count         int            `json:"count"`
+}
+
+// VoucherCreatedResponse is the only time a voucher code is ever returned
+type VoucherCreatedResponse struct {
+	Code    string  `json:"code"`
+	Voucher Voucher `json:"voucher"`
+}
+
+type VoucherBatchResponse struct {
+	Vouchers []VoucherCreatedResponse `json:"vouchers"`
+	Count    int                      `json:"count"`
+}
+
+type VoucherListResponse struct {
+	Vouchers []Voucher `json:"vouchers"`
+	Count    int       `json:"count"`
+}
+
+type PINStatusResponse struct {
+	Set         bool       `json:"set"`
+	Threshold   uint       `json:"threshold"` // Transfers of at least this many points need the PIN
+	LockedUntil *time.Time `json:"locked_until"`
+}
+
+// MFAChallengeResponse is returned by /login instead of a token when the user has 2FA
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`  // Exchange at /login/mfa together with a code
+	ExpiresIn   int    `json:"expires_in"` // Seconds
+}
+
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"` // PNG data URI of otpauth_uri
+}
+
+// RecoveryCodesResponse is the only time recovery codes are ever returned
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type SecurityEventListResponse struct {
+	Events []SecurityEvent `json:"events"`
+	Count  int             `json:"count"`
+}
+
+// MessageResponse is returned by endpoints that have nothing to report but an outcome
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type ProfileChangeListResponse struct {
+	Changes []ProfileChange `json:"changes"`
+	Count   int             `json:"count"`
+}
+
+type SessionListResponse struct {
+	Sessions []Session `json:"sessions"`
+	Count    int       `json:"count"`
+}
+
+// AccountExport is everything stored about a user, for download by the user
+type AccountExport struct {
+	ExportedAt      time.Time             `json:"exported_at"`
+	Profile         User                  `json:"profile"`
+	Wallets         []Wallet              `json:"wallets"`
+	Transfers       []Transfer            `json:"transfers"`
+	Orders          []RedemptionOrder     `json:"orders"`
+	Escrows         []Escrow              `json:"escrows"`
+	PaymentRequests []PaymentRequest      `json:"payment_requests"`
+	PaymentShares   []PaymentRequestShare `json:"payment_shares"`
+	Vouchers        []Voucher             `json:"vouchers"`
+	Referrals       []Referral            `json:"referrals"`
+	Notifications   []Notification        `json:"notifications"`
+	ProfileChanges  []ProfileChange       `json:"profile_changes"`
+	SecurityEvents  []SecurityEvent       `json:"security_events"`
+	Sessions        []Session             `json:"sessions"`
+	APIKeys         []UserAPIKey          `json:"api_keys"`
+}
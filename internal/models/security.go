@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginThrottle counts recent failed logins for one email address or one IP.
+// Emails are tracked whether or not an account exists, so lockouts do not
+// reveal which addresses are registered. They are keyed by blind index, so
+// no email address is stored in plaintext.
+type LoginThrottle struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	Kind          string     `json:"kind" gorm:"not null;uniqueIndex:idx_login_throttles_key"` // account, ip
+	Key           string     `json:"key" gorm:"not null;uniqueIndex:idx_login_throttles_key"`  // Blind index of the email, or IP address
+	Failures      int        `json:"failures" gorm:"default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// SecurityEvent records an authentication event for later review
+type SecurityEvent struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	Type       string    `json:"type" gorm:"not null;index"` // login_failed, account_locked, ip_locked, account_unlocked, password_reset_requested, password_reset, password_changed, account_closed, session_revoked, api_key_created, api_key_revoked
+	UserID     *uint     `json:"user_id" gorm:"index"`       // nil when the email matches no account
+	EmailIndex string    `json:"email_index" gorm:"index"`   // Blind index of the email the event concerns
+	IP         string    `json:"ip"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
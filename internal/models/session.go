@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one login of a user on one device. Its ID travels in the JWT as
+// the sid claim, so revoking the session signs that device out.
+type Session struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	DeviceLabel string     `json:"device_label"` // Name the client gave at login, e.g. "Pixel 8"
+	IP          string     `json:"ip"`           // Address of the latest request
+	UserAgent   string     `json:"user_agent"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"index"` // When the session's token expires
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Current     bool       `json:"current" gorm:"-"` // Whether the listing was requested with this session's token
+}
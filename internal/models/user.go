@@ -6,29 +6,88 @@ import (
 
 // User model
 type User struct {
-	ID           uint      `json:"id" gorm:"primarykey"`
-	Email        string    `json:"email" gorm:"unique;not null"`
-	Password     string    `json:"-" gorm:"not null"` // "-" excludes from JSON
-	FirstName    string    `json:"first_name" gorm:"not null"`
-	LastName     string    `json:"last_name" gorm:"not null"`
-	PhoneNumber  string    `json:"phone_number"`
-	DOB          time.Time `json:"dob"`
-	LBKCode      string    `json:"lbk_code" gorm:"unique;not null"` // LBK identification code
-	PointBalance uint      `json:"point_balance" gorm:"default:0"`  // Point balance
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uint       `json:"id" gorm:"primarykey"`
+	Email           string     `json:"email" gorm:"unique;not null;serializer:pii"` // Encrypted at rest, see package pii
+	EmailIndex      string     `json:"-"`                                           // Blind index for lookups (unique, see database.NewDatabase)
+	EmailVerified   bool       `json:"email_verified" gorm:"default:false"`         // Proven by following the emailed verification link
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Password        string     `json:"-" gorm:"not null"` // "-" excludes from JSON
+	FirstName       string     `json:"first_name" gorm:"not null"`
+	LastName        string     `json:"last_name" gorm:"not null"`
+	PhoneNumber     string     `json:"phone_number" gorm:"serializer:pii"`  // E.164, e.g. +66812345678; encrypted at rest
+	PhoneIndex      string     `json:"-" gorm:"index"`                      // Blind index for lookups
+	PhoneVerified   bool       `json:"phone_verified" gorm:"default:false"` // Confirmed by SMS code; a verified number belongs to one user only
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at"`
+	DOB             time.Time  `json:"dob" gorm:"type:text;serializer:pii"` // Encrypted at rest, so stored as text
+	LBKCode         string     `json:"lbk_code" gorm:"unique;not null"`     // LBK identification code
+	PointBalance    uint       `json:"point_balance" gorm:"default:0"`      // Balance of the default currency wallet
+	Role            string     `json:"role" gorm:"default:'user'"`          // user, admin
+	ReferralCode    *string    `json:"referral_code"`                       // Shareable code for inviting others (unique, see database.NewDatabase)
+	DeviceID        string     `json:"-"`                                   // Device used at registration, for referral abuse checks
+	PINHash         string     `json:"-"`                                   // Transaction PIN for high-value transfers, hashed like the password
+	PINFailures     int        `json:"-" gorm:"default:0"`                  // Wrong PINs since the last correct one
+	PINLockedUntil  *time.Time `json:"-"`
+	MFAEnabled      bool       `json:"mfa_enabled" gorm:"default:false"` // TOTP second factor required at login
+	TOTPSecret      string     `json:"-"`                                // Set on enrollment, active once confirmed
+	TOTPLastStep    int64      `json:"-" gorm:"default:0"`               // Last accepted time step, so a code works only once
+	MFAFailures     int        `json:"-" gorm:"default:0"`
+	MFALockedUntil  *time.Time `json:"-"`
+	TokenVersion    int        `json:"-" gorm:"default:0"`  // Bumped to revoke every JWT issued before, e.g. on password reset
+	ClosedAt        *time.Time `json:"closed_at,omitempty"` // Set when the user closes the account; personal data is erased but the row and LBK code are kept
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // Transfer model for point transfers
 type Transfer struct {
 	ID         uint      `json:"id" gorm:"primarykey"`
-	FromUserID uint      `json:"from_user_id" gorm:"not null"`
-	ToUserID   uint      `json:"to_user_id" gorm:"not null"`
-	FromUser   User      `json:"from_user" gorm:"foreignKey:FromUserID"`
-	ToUser     User      `json:"to_user" gorm:"foreignKey:ToUserID"`
+	FromUserID *uint     `json:"from_user_id"` // nil for points issued by the system, e.g. campaign bonuses
+	ToUserID   *uint     `json:"to_user_id"`   // nil for points leaving the system, e.g. expired points
+	FromUser   *User     `json:"from_user,omitempty" gorm:"foreignKey:FromUserID"`
+	ToUser     *User     `json:"to_user,omitempty" gorm:"foreignKey:ToUserID"`
 	Amount     uint      `json:"amount" gorm:"not null"`
+	Currency   string    `json:"currency" gorm:"default:'PTS'"`
 	Message    string    `json:"message"`
-	Status     string    `json:"status" gorm:"default:'completed'"` // completed, failed, pending
+	ToAlias    string    `json:"to_alias,omitempty"`                // Masked phone or email the sender addressed the recipient by
+	Type       string    `json:"type" gorm:"default:'transfer'"`    // transfer, expiry, bonus, redemption, refund, payment, issue, conversion, escrow, voucher
+	Status     string    `json:"status" gorm:"default:'completed'"` // completed, failed, pending, expired, held, disputed, refunded
+	APIKeyID   *uint     `json:"api_key_id,omitempty" gorm:"index"` // Personal API key the sender used, nil for transfers made in the app
+	Escrow     *Escrow   `json:"escrow,omitempty" gorm:"foreignKey:TransferID"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
+
+// RecoveryCode is a single-use code that replaces the TOTP code when the
+// authenticator is lost. Only a hash is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// UserToken is a single-use, time-limited token sent to the user out of band,
+// e.g. in a password reset link. Only a hash is stored.
+type UserToken struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null"` // password_reset, email_verification, mfa_login
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// PhoneVerification is a one-time code texted to a phone number. Only a hash
+// of the code is stored.
+type PhoneVerification struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	PhoneNumber string     `json:"phone_number" gorm:"not null;serializer:pii"`
+	CodeHash    string     `json:"-" gorm:"not null"`
+	Attempts    int        `json:"attempts" gorm:"default:0"` // Wrong codes entered
+	ExpiresAt   time.Time  `json:"expires_at"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Voucher locks points behind a printable code that any user can redeem once.
+// Only a hash of the code is stored; the code itself is shown when it is issued.
+type Voucher struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	CodeHash   string     `json:"-" gorm:"not null;uniqueIndex"`
+	CodeHint   string     `json:"code_hint"` // Last group of the code, to tell vouchers apart
+	IssuerID   uint       `json:"issuer_id" gorm:"not null;index"`
+	Funding    string     `json:"funding" gorm:"not null"` // wallet (bought by the issuer), campaign (issued by an admin)
+	Amount     uint       `json:"amount" gorm:"not null"`
+	Currency   string     `json:"currency" gorm:"not null"`
+	Message    string     `json:"message"`
+	Status     string     `json:"status" gorm:"default:'active';index"` // active, redeemed, expired
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`     // Unredeemed value is refunded after this
+	RedeemedBy *uint      `json:"redeemed_by"`
+	RedeemedAt *time.Time `json:"redeemed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultCurrency is the loyalty points program every user starts with. Its
+// balance is also mirrored into User.PointBalance for older clients.
+const DefaultCurrency = "PTS"
+
+// Currency is a point program users can hold a balance in, e.g. loyalty
+// points, gift points or event tokens
+type Currency struct {
+	Code      string    `json:"code" gorm:"primarykey"` // Short upper-case code, e.g. PTS
+	Name      string    `json:"name" gorm:"not null"`
+	Active    bool      `json:"active" gorm:"default:true"` // Inactive currencies can no longer be transferred or issued
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Wallet holds a user's balance in one currency
+type Wallet struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_wallets_user_currency"`
+	Currency  string    `json:"currency" gorm:"not null;uniqueIndex:idx_wallets_user_currency"`
+	Balance   uint      `json:"balance" gorm:"default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,186 @@
+// Package pii encrypts personal data at rest. Each value is sealed with its
+// own random data key, which is in turn encrypted with a key-encryption key
+// from a KeyProvider (envelope encryption). A sealed value is bound to the
+// column it is stored in, so it cannot be copied into another column. Blind
+// indexes, keyed hashes of the plaintext, let equality lookups work without
+// decrypting every row.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Prefix of every sealed value. Values without it are plaintext written
+// before encryption was enabled, and are returned as they are.
+const Prefix = "pii:v2:"
+
+// legacyPrefix marks values sealed before they were bound to their column.
+// They still open anywhere until SealPII reseals them.
+const legacyPrefix = "pii:v1:"
+
+// Cipher seals and opens values with the keys of a KeyProvider
+type Cipher struct {
+	keys KeyProvider
+}
+
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// Seal encrypts plaintext for column, given as table.column, under a new data
+// key wrapped with the active key. The result has the form
+// pii:v2:<key ID>:<wrapped data key>:<ciphertext>, and only opens for the
+// same column.
+func (c *Cipher) Seal(plaintext, column string) (string, error) {
+	keyID := c.keys.ActiveKeyID()
+	kek, err := c.keys.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(kek, dek, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	data, err := seal(dek, []byte(plaintext), dataAAD(keyID, column))
+	if err != nil {
+		return "", err
+	}
+
+	return Prefix + keyID + ":" + encode(wrapped) + ":" + encode(data), nil
+}
+
+// Open decrypts a value Seal made for column. Plaintext values are returned
+// unchanged.
+func (c *Cipher) Open(value, column string) (string, error) {
+	var prefix string
+	switch {
+	case strings.HasPrefix(value, Prefix):
+		prefix = Prefix
+	case strings.HasPrefix(value, legacyPrefix):
+		prefix = legacyPrefix
+	default:
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed sealed value")
+	}
+	keyID := parts[0]
+	kek, err := c.keys.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed sealed value")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed sealed value")
+	}
+
+	dek, err := open(kek, wrapped, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	aad := dataAAD(keyID, column)
+	if prefix == legacyPrefix {
+		aad = []byte(keyID)
+	}
+	plaintext, err := open(dek, data, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Current reports whether value is sealed with the active key and bound to
+// its column
+func (c *Cipher) Current(value string) bool {
+	return strings.HasPrefix(value, Prefix+c.keys.ActiveKeyID()+":")
+}
+
+// EmailIndex is the blind index of an email address, ignoring case
+func (c *Cipher) EmailIndex(email string) string {
+	return c.Index("email", strings.ToLower(strings.TrimSpace(email)))
+}
+
+// PhoneIndex is the blind index of an E.164 phone number
+func (c *Cipher) PhoneIndex(number string) string {
+	return c.Index("phone", number)
+}
+
+// Index returns the blind index of value. kind keeps indexes of different
+// columns apart, so equal values in two columns do not match. Empty values
+// have an empty index.
+func (c *Cipher) Index(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.keys.IndexKey())
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return encode(mac.Sum(nil))
+}
+
+// dataAAD binds a value's ciphertext to its key ID and column
+func dataAAD(keyID, column string) []byte {
+	return []byte(keyID + "\x00" + column)
+}
+
+// seal encrypts plaintext with AES-256-GCM, prepending the random nonce
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed sealed value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errors.New("cannot decrypt sealed value")
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,297 @@
+package pii
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+// Column the tests seal values for
+const testColumn = "users.email"
+
+func testCipher(t *testing.T, active string, keys map[string][]byte, indexKey []byte) *Cipher {
+	t.Helper()
+	provider, err := NewStaticKeyProvider(active, keys, indexKey)
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider: %v", err)
+	}
+	return NewCipher(provider)
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"email", "jane@example.com"},
+		{"phone", "+66812345678"},
+		{"unicode", "สมชาย ใจดี"},
+		{"contains separator", "a:b:c"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := c.Seal(tt.plaintext, testColumn)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			if !strings.HasPrefix(sealed, Prefix+"k1:") {
+				t.Errorf("sealed value %q lacks the key prefix", sealed)
+			}
+			if tt.plaintext != "" && strings.Contains(sealed, tt.plaintext) {
+				t.Errorf("sealed value %q contains the plaintext", sealed)
+			}
+
+			opened, err := c.Open(sealed, testColumn)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if opened != tt.plaintext {
+				t.Errorf("Open = %q, want %q", opened, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshDataKeys(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+
+	first, err := c.Seal("jane@example.com", testColumn)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	second, err := c.Seal("jane@example.com", testColumn)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if first == second {
+		t.Error("sealing the same value twice gave the same ciphertext")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	sealed, err := c.Seal("jane@example.com", testColumn)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	parts := strings.Split(strings.TrimPrefix(sealed, Prefix), ":")
+	tampered := "A" + parts[2][1:]
+	if tampered == parts[2] {
+		tampered = "B" + parts[2][1:]
+	}
+
+	tests := []struct {
+		name   string
+		cipher *Cipher
+		value  string
+	}{
+		{"wrong key under the same ID", testCipher(t, "k1", map[string][]byte{"k1": testKey(2)}, testKey(9)), sealed},
+		{"unknown key ID", testCipher(t, "k2", map[string][]byte{"k2": testKey(1)}, testKey(9)), sealed},
+		{"key ID swapped", c, Prefix + "k2:" + parts[1] + ":" + parts[2]},
+		{"tampered ciphertext", c, Prefix + parts[0] + ":" + parts[1] + ":" + tampered},
+		{"missing part", c, Prefix + parts[0] + ":" + parts[1]},
+		{"not base64", c, Prefix + parts[0] + ":!!!:" + parts[2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if opened, err := tt.cipher.Open(tt.value, testColumn); err == nil {
+				t.Errorf("Open = %q, want an error", opened)
+			}
+		})
+	}
+}
+
+func TestOpenRejectsOtherColumns(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	sealed, err := c.Seal("+66812345678", "users.phone_number")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		column string
+		ok     bool
+	}{
+		{"same column", "users.phone_number", true},
+		{"other column of the table", "users.email", false},
+		{"same column of another table", "phone_verifications.phone_number", false},
+		{"no column", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := c.Open(sealed, tt.column)
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("Open = %q, want an error", opened)
+				}
+				return
+			}
+			if err != nil || opened != "+66812345678" {
+				t.Errorf("Open = %q, %v", opened, err)
+			}
+		})
+	}
+}
+
+// sealLegacy seals plaintext the way values were sealed before they were
+// bound to their column
+func sealLegacy(t *testing.T, key []byte, keyID, plaintext string) string {
+	t.Helper()
+	dek := testKey(7)
+	wrapped, err := seal(key, dek, []byte(keyID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := seal(dek, []byte(plaintext), []byte(keyID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return legacyPrefix + keyID + ":" + encode(wrapped) + ":" + encode(data)
+}
+
+func TestOpenLegacyValues(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	legacy := sealLegacy(t, testKey(1), "k1", "jane@example.com")
+
+	for _, column := range []string{testColumn, "users.phone_number"} {
+		opened, err := c.Open(legacy, column)
+		if err != nil || opened != "jane@example.com" {
+			t.Errorf("Open for %s = %q, %v", column, opened, err)
+		}
+	}
+	if c.Current(legacy) {
+		t.Error("a value not bound to its column is current, so it would never be resealed")
+	}
+}
+
+func TestOpenPassesPlaintextThrough(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+
+	for _, value := range []string{"", "jane@example.com", "pii:v9:future"} {
+		opened, err := c.Open(value, testColumn)
+		if err != nil {
+			t.Fatalf("Open(%q): %v", value, err)
+		}
+		if opened != value {
+			t.Errorf("Open(%q) = %q", value, opened)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	rotated := testCipher(t, "k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9))
+	retired := testCipher(t, "k2", map[string][]byte{"k2": testKey(2)}, testKey(9))
+
+	sealed, err := old.Seal("jane@example.com", testColumn)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	resealed, err := rotated.Seal("jane@example.com", testColumn)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cipher   *Cipher
+		value    string
+		current  bool
+		openable bool
+	}{
+		{"old value with old keys", old, sealed, true, true},
+		{"old value after adding a key", rotated, sealed, false, true},
+		{"resealed value", rotated, resealed, true, true},
+		{"old value after retiring its key", retired, sealed, false, false},
+		{"resealed value after retiring the old key", retired, resealed, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cipher.Current(tt.value); got != tt.current {
+				t.Errorf("Current = %v, want %v", got, tt.current)
+			}
+			opened, err := tt.cipher.Open(tt.value, testColumn)
+			if !tt.openable {
+				if err == nil {
+					t.Error("Open succeeded with the key retired")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if opened != "jane@example.com" {
+				t.Errorf("Open = %q", opened)
+			}
+		})
+	}
+}
+
+func TestBlindIndexes(t *testing.T) {
+	c := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+	rotatedKEK := testCipher(t, "k2", map[string][]byte{"k2": testKey(2)}, testKey(9))
+	otherIndexKey := testCipher(t, "k1", map[string][]byte{"k1": testKey(1)}, testKey(8))
+
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{"same email", c.EmailIndex("jane@example.com"), c.EmailIndex("jane@example.com"), true},
+		{"email case and spaces", c.EmailIndex(" Jane@Example.COM "), c.EmailIndex("jane@example.com"), true},
+		{"different emails", c.EmailIndex("jane@example.com"), c.EmailIndex("john@example.com"), false},
+		{"phone is exact", c.PhoneIndex("+66812345678"), c.PhoneIndex("+66812345679"), false},
+		{"kinds kept apart", c.EmailIndex("+66812345678"), c.PhoneIndex("+66812345678"), false},
+		{"independent of the encryption key", rotatedKEK.EmailIndex("jane@example.com"), c.EmailIndex("jane@example.com"), true},
+		{"index key changes every index", otherIndexKey.EmailIndex("jane@example.com"), c.EmailIndex("jane@example.com"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.equal {
+				t.Errorf("indexes %q and %q: equal = %v, want %v", tt.a, tt.b, tt.a == tt.b, tt.equal)
+			}
+		})
+	}
+
+	if index := c.EmailIndex("jane@example.com"); strings.Contains(index, "@") || strings.Contains(index, "jane") {
+		t.Errorf("index %q reveals the email", index)
+	}
+	if index := c.EmailIndex("  "); index != "" {
+		t.Errorf("EmailIndex of a blank email = %q, want empty", index)
+	}
+}
+
+func TestStaticKeyProviderValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		active   string
+		keys     map[string][]byte
+		indexKey []byte
+		wantErr  bool
+	}{
+		{"single key becomes active", "", map[string][]byte{"k1": testKey(1)}, testKey(9), false},
+		{"explicit active key", "k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9), false},
+		{"no keys", "", map[string][]byte{}, testKey(9), true},
+		{"active key missing", "k3", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9), true},
+		{"ambiguous active key", "", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9), true},
+		{"short key", "k1", map[string][]byte{"k1": testKey(1)[:16]}, testKey(9), true},
+		{"ID with separator", "k:1", map[string][]byte{"k:1": testKey(1)}, testKey(9), true},
+		{"short index key", "k1", map[string][]byte{"k1": testKey(1)}, testKey(9)[:16], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStaticKeyProvider(tt.active, tt.keys, tt.indexKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package pii
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the length of every key in bytes (AES-256 and HMAC-SHA256)
+const KeySize = 32
+
+// KeyProvider supplies the key-encryption keys that protect the data key of
+// each sealed value, and the key of the blind indexes
+type KeyProvider interface {
+	// ActiveKeyID names the key that seals new values
+	ActiveKeyID() string
+	// Key returns a key by ID. Retired keys must stay available until
+	// rotate-pii-keys has re-encrypted every value sealed with them.
+	Key(id string) ([]byte, error)
+	// IndexKey keys the blind indexes. Changing it breaks lookups until
+	// rotate-pii-keys has recomputed them.
+	IndexKey() []byte
+}
+
+// StaticKeyProvider holds keys loaded once at startup
+type StaticKeyProvider struct {
+	active   string
+	keys     map[string][]byte
+	indexKey []byte
+}
+
+func NewStaticKeyProvider(active string, keys map[string][]byte, indexKey []byte) (*StaticKeyProvider, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys configured")
+	}
+	if active == "" && len(keys) == 1 {
+		for id := range keys {
+			active = id
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not configured", active)
+	}
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, ":, ") {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes", id, KeySize)
+		}
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("index key must be %d bytes", KeySize)
+	}
+
+	return &StaticKeyProvider{active: active, keys: keys, indexKey: indexKey}, nil
+}
+
+// NewEnvKeyProvider reads keys in the form "id:base64key,id:base64key", as
+// found in environment variables
+func NewEnvKeyProvider(keys, active, indexKey string) (*StaticKeyProvider, error) {
+	parsed := map[string][]byte{}
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("key %q must be in the form id:base64key", entry)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", id, err)
+		}
+		parsed[id] = key
+	}
+
+	index, err := decodeKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("index key: %v", err)
+	}
+
+	return NewStaticKeyProvider(active, parsed, index)
+}
+
+// keyFile is the JSON layout read by NewFileKeyProvider
+type keyFile struct {
+	ActiveKey string            `json:"active_key"`
+	Keys      map[string]string `json:"keys"`
+	IndexKey  string            `json:"index_key"`
+}
+
+// NewFileKeyProvider reads keys from a JSON file such as
+// {"active_key": "k2", "keys": {"k1": "base64", "k2": "base64"}, "index_key": "base64"}
+func NewFileKeyProvider(path string) (*StaticKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid key file: %v", err)
+	}
+
+	keys := map[string][]byte{}
+	for id, encoded := range file.Keys {
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", id, err)
+		}
+		keys[id] = key
+	}
+
+	index, err := decodeKey(file.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("index key: %v", err)
+	}
+
+	return NewStaticKeyProvider(file.ActiveKey, keys, index)
+}
+
+// NewDevelopmentKeyProvider returns fixed, publicly known keys with ID "dev",
+// so the server runs without configuration. Never use it for real data.
+func NewDevelopmentKeyProvider() *StaticKeyProvider {
+	key := sha256.Sum256([]byte("fiber-api development pii key"))
+	index := sha256.Sum256([]byte("fiber-api development pii index key"))
+	return &StaticKeyProvider{
+		active:   "dev",
+		keys:     map[string][]byte{"dev": key[:]},
+		indexKey: index[:],
+	}
+}
+
+func (p *StaticKeyProvider) ActiveKeyID() string {
+	return p.active
+}
+
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+func (p *StaticKeyProvider) IndexKey() []byte {
+	return p.indexKey
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+	return key, nil
+}
@@ -0,0 +1,150 @@
+package pii
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// The cipher used by the serializer and the package-level helpers. GORM
+// serializers are registered globally, so it is process-wide as well.
+var std *Cipher
+
+// Use installs c as the cipher of columns tagged serializer:pii and of the
+// helpers below. It must be called before the first query on such a model.
+func Use(c *Cipher) {
+	std = c
+	schema.RegisterSerializer("pii", Serializer{})
+}
+
+// EmailIndex is the blind index of an email address with the installed cipher
+func EmailIndex(email string) string {
+	return std.EmailIndex(email)
+}
+
+// PhoneIndex is the blind index of a phone number with the installed cipher
+func PhoneIndex(number string) string {
+	return std.PhoneIndex(number)
+}
+
+// Sealed wraps a plaintext string or time.Time for map updates, which GORM
+// writes without running serializers. It is encrypted when written.
+type Sealed struct {
+	value  interface{}
+	column string
+}
+
+// Seal wraps value for the column of table it is written to
+func Seal(table, column string, value interface{}) Sealed {
+	return Sealed{value: value, column: Column(table, column)}
+}
+
+func (s Sealed) Value() (driver.Value, error) {
+	return sealValue(s.value, s.column)
+}
+
+// Column names a column for Cipher.Seal and Cipher.Open
+func Column(table, column string) string {
+	return table + "." + column
+}
+
+// fieldColumn names the column of a model field
+func fieldColumn(field *schema.Field) string {
+	return Column(field.Schema.Table, field.DBName)
+}
+
+// Serializer encrypts string and time.Time fields on write and decrypts them
+// on read. Empty strings and zero times are stored unencrypted, as "" and NULL.
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value interface{}
+	switch raw := dbValue.(type) {
+	case time.Time:
+		// Plaintext date read from a datetime column
+		value = raw
+	case nil:
+		value = ""
+	case []byte:
+		value = string(raw)
+	case string:
+		value = raw
+	default:
+		return fmt.Errorf("pii: cannot read %T into %s", dbValue, field.Name)
+	}
+
+	if text, ok := value.(string); ok && text != "" {
+		plaintext, err := std.Open(text, fieldColumn(field))
+		if err != nil {
+			return fmt.Errorf("pii: %s: %v", field.Name, err)
+		}
+		value = plaintext
+	}
+
+	switch field.FieldType {
+	case reflect.TypeOf(""):
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339)
+		}
+	case reflect.TypeOf(time.Time{}):
+		if text, ok := value.(string); ok {
+			t, err := parseTime(text)
+			if err != nil {
+				return fmt.Errorf("pii: %s: %v", field.Name, err)
+			}
+			value = t
+		}
+	default:
+		return fmt.Errorf("pii: unsupported field type %s", field.FieldType)
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(reflect.ValueOf(value))
+	return nil
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	return sealValue(fieldValue, fieldColumn(field))
+}
+
+func sealValue(value interface{}, column string) (driver.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		return std.Seal(v, column)
+	case time.Time:
+		if v.IsZero() {
+			return nil, nil
+		}
+		return std.Seal(v.Format(time.RFC3339), column)
+	default:
+		return nil, fmt.Errorf("pii: cannot seal %T", value)
+	}
+}
+
+// Layouts of dates written before encryption, by the SQLite driver, and by sealValue
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseTime(text string) (time.Time, error) {
+	if text == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse date %q", text)
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccountService lets users download what is stored about them and close
+// their account
+type AccountService struct {
+	db     *gorm.DB
+	ledger *PointLedger
+	mfa    *MFAService
+	guard  *LoginGuard
+}
+
+func NewAccountService(db *gorm.DB, ledger *PointLedger, mfa *MFAService, guard *LoginGuard) *AccountService {
+	return &AccountService{db: db, ledger: ledger, mfa: mfa, guard: guard}
+}
+
+// Export collects the user's profile and everything linked to it. Other users
+// appear only by ID, so the export holds no one else's personal data.
+func (s *AccountService) Export(userID uint) (*models.AccountExport, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := models.AccountExport{
+		ExportedAt: time.Now(),
+		Profile:    *user,
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&export.Wallets, s.db.Where("user_id = ?", userID)},
+		{&export.Transfers, s.db.Where("from_user_id = ? OR to_user_id = ?", userID, userID)},
+		{&export.Orders, s.db.Preload("Item").Where("user_id = ?", userID)},
+		{&export.Escrows, s.db.Where("sender_id = ? OR recipient_id = ?", userID, userID)},
+		{&export.PaymentRequests, s.db.Preload("Shares").Where("requester_id = ?", userID)},
+		{&export.PaymentShares, s.db.Where("user_id = ?", userID)},
+		{&export.Vouchers, s.db.Where("issuer_id = ? OR redeemed_by = ?", userID, userID)},
+		{&export.Referrals, s.db.Where("referrer_id = ? OR referee_id = ?", userID, userID)},
+		{&export.Notifications, s.db.Where("user_id = ?", userID)},
+		{&export.ProfileChanges, s.db.Where("user_id = ?", userID)},
+		{&export.SecurityEvents, s.db.Where("user_id = ?", userID)},
+		{&export.Sessions, s.db.Where("user_id = ?", userID)},
+		{&export.APIKeys, s.db.Where("user_id = ?", userID)},
+	}
+	for _, q := range queries {
+		if err := q.query.Order("id").Find(q.dest).Error; err != nil {
+			return nil, errors.New("failed to export account")
+		}
+	}
+
+	return &export, nil
+}
+
+// Close erases the user's personal data and signs out every session. The row
+// stays so transfers keep their parties and the LBK code is never handed out
+// again. Any remaining balance must first be swept to another account.
+func (s *AccountService) Close(userID uint, req models.CloseAccountRequest, ip string) (*models.User, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ClosedAt != nil {
+		return nil, errors.New("account already closed")
+	}
+	if err := s.guard.VerifyPassword(user, req.Password, ip); err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		if req.Code == "" && req.RecoveryCode == "" {
+			return nil, errors.New("mfa code required")
+		}
+		if err := s.mfa.Verify(userID, req.Code, req.RecoveryCode); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkSettled(tx, userID); err != nil {
+			return err
+		}
+		if err := s.sweep(tx, userID, req.SweepToLBKCode); err != nil {
+			return err
+		}
+
+		// Requests others still owe on can no longer be paid to a closed account
+		if err := tx.Model(&models.PaymentRequest{}).
+			Where("requester_id = ? AND status = ?", userID, "open").
+			Updates(map[string]interface{}{"status": "cancelled", "closed_at": now}).Error; err != nil {
+			return errors.New("failed to cancel payment requests")
+		}
+
+		// Guard against the account being closed twice at once
+		closedEmail := fmt.Sprintf("closed-%d@closed.invalid", userID)
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND closed_at IS NULL", userID).
+			Updates(map[string]interface{}{
+				"email":             pii.Seal("users", "email", closedEmail),
+				"email_index":       pii.EmailIndex(closedEmail),
+				"email_verified":    false,
+				"email_verified_at": nil,
+				"password":          "",
+				"first_name":        "Closed",
+				"last_name":         "Account",
+				"phone_number":      "",
+				"phone_index":       "",
+				"phone_verified":    false,
+				"phone_verified_at": nil,
+				"dob":               nil,
+				"referral_code":     nil,
+				"device_id":         "",
+				"pin_hash":          "",
+				"pin_failures":      0,
+				"pin_locked_until":  nil,
+				"mfa_enabled":       false,
+				"totp_secret":       "",
+				"totp_last_step":    0,
+				"mfa_failures":      0,
+				"mfa_locked_until":  nil,
+				"token_version":     gorm.Expr("token_version + 1"),
+				"closed_at":         now,
+			})
+		if result.Error != nil {
+			return errors.New("failed to close account")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("account already closed")
+		}
+
+		for _, model := range []interface{}{
+			&models.RecoveryCode{}, &models.UserToken{}, &models.PhoneVerification{}, &models.Notification{}, &models.Session{},
+			&models.UserAPIKey{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return errors.New("failed to erase account data")
+			}
+		}
+
+		// History stays, without the values that were personal data
+		if err := tx.Model(&models.ProfileChange{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"old_value": "", "new_value": ""}).Error; err != nil {
+			return errors.New("failed to erase account data")
+		}
+
+		emailIndex := pii.EmailIndex(user.Email)
+		if err := tx.Model(&models.SecurityEvent{}).Where("user_id = ? OR email_index = ?", userID, emailIndex).
+			Update("email_index", pii.EmailIndex(closedEmail)).Error; err != nil {
+			return errors.New("failed to erase account data")
+		}
+		if err := tx.Where("kind = ? AND key = ?", "account", emailIndex).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return errors.New("failed to erase account data")
+		}
+
+		return s.guard.Record(tx, "account_closed", &userID, closedEmail, ip, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.user(userID)
+}
+
+// checkSettled fails while points are on their way to or from the user, since
+// they would land in a closed account
+func (s *AccountService) checkSettled(tx *gorm.DB, userID uint) error {
+	checks := []struct {
+		model interface{}
+		query string
+		args  []interface{}
+		err   string
+	}{
+		{&models.Escrow{}, "(sender_id = ? OR recipient_id = ?) AND status IN ?",
+			[]interface{}{userID, userID, []string{"held", "disputed"}}, "account has open escrows"},
+		{&models.Voucher{}, "issuer_id = ? AND funding = ? AND status = ?",
+			[]interface{}{userID, "wallet", "active"}, "account has active vouchers"},
+		{&models.RedemptionOrder{}, "user_id = ? AND status IN ?",
+			[]interface{}{userID, []string{"pending", "shipped"}}, "account has open redemption orders"},
+	}
+	for _, check := range checks {
+		var count int64
+		if err := tx.Model(check.model).Where(check.query, check.args...).Count(&count).Error; err != nil {
+			return errors.New("database error")
+		}
+		if count > 0 {
+			return errors.New(check.err)
+		}
+	}
+	return nil
+}
+
+// sweep moves every remaining balance to the account with lbkCode, recording
+// one transfer per currency
+func (s *AccountService) sweep(tx *gorm.DB, userID uint, lbkCode string) error {
+	var wallets []models.Wallet
+	if err := tx.Where("user_id = ? AND balance > 0", userID).Order("currency").Find(&wallets).Error; err != nil {
+		return errors.New("database error")
+	}
+	if len(wallets) == 0 {
+		return nil
+	}
+
+	lbkCode = strings.ToUpper(strings.TrimSpace(lbkCode))
+	if lbkCode == "" {
+		return errors.New("balance must be zero or swept")
+	}
+	var recipient models.User
+	if err := tx.Where("lbk_code = ? AND closed_at IS NULL", lbkCode).First(&recipient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("recipient user not found")
+		}
+		return errors.New("database error")
+	}
+	if recipient.ID == userID {
+		return errors.New("cannot transfer points to yourself")
+	}
+
+	for _, wallet := range wallets {
+		if err := s.ledger.DebitCurrency(tx, userID, wallet.Currency, wallet.Balance); err != nil {
+			return err
+		}
+		if err := s.ledger.CreditCurrency(tx, recipient.ID, wallet.Currency, wallet.Balance, "transfer"); err != nil {
+			return err
+		}
+		transfer := models.Transfer{
+			FromUserID: &userID,
+			ToUserID:   &recipient.ID,
+			Amount:     wallet.Balance,
+			Currency:   wallet.Currency,
+			Message:    "Account closure",
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+	}
+	return nil
+}
+
+func (s *AccountService) user(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
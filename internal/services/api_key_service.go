@@ -0,0 +1,291 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scopes of personal API keys
+const (
+	ScopeReadBalance = "balance:read" // Balances and wallets
+	ScopeReadHistory = "history:read" // Transfer history
+	ScopeTransfer    = "transfer"     // Sending points, up to the key's transfer limit
+)
+
+var apiKeyScopes = []string{ScopeReadBalance, ScopeReadHistory, ScopeTransfer}
+
+const (
+	userKeyPrefix = "uk_"
+
+	// Most unrevoked keys one user may have
+	maxAPIKeysPerUser = 10
+
+	// Window over which a key's transfer limit applies
+	apiKeyLimitWindow = 24 * time.Hour
+
+	// A key's last use is written at most this often, not on every request
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService issues personal API keys that let a user's integrations call
+// selected endpoints without storing the password
+type APIKeyService struct {
+	db    *gorm.DB
+	mfa   *MFAService
+	guard *LoginGuard
+}
+
+func NewAPIKeyService(db *gorm.DB, mfa *MFAService, guard *LoginGuard) *APIKeyService {
+	return &APIKeyService{db: db, mfa: mfa, guard: guard}
+}
+
+// Create issues a new key for the user. The returned key is not stored and
+// cannot be shown again.
+func (s *APIKeyService) Create(userID uint, req models.CreateUserAPIKeyRequest, ip string) (*models.UserAPIKeyCreatedResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name must not be empty")
+	}
+	if len([]rune(name)) > 100 {
+		return nil, errors.New("name is too long")
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	transfers := containsScope(scopes, ScopeTransfer)
+	if transfers && req.TransferLimit == 0 {
+		return nil, errors.New("transfer_limit is required with the transfer scope")
+	}
+	if !transfers && req.TransferLimit != 0 {
+		return nil, errors.New("transfer_limit needs the transfer scope")
+	}
+	if req.ExpiresInDays < 0 {
+		return nil, errors.New("expires_in_days must not be negative")
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guard.VerifyPassword(user, req.Password, ip); err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		if req.Code == "" && req.RecoveryCode == "" {
+			return nil, errors.New("mfa code required")
+		}
+		if err := s.mfa.Verify(userID, req.Code, req.RecoveryCode); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix, err := utils.RandomString(prefixAlphabet, 8)
+	if err != nil {
+		return nil, errors.New("failed to generate api key")
+	}
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate api key")
+	}
+	key := userKeyPrefix + prefix + "_" + secret
+
+	apiKey := models.UserAPIKey{
+		UserID:        userID,
+		Name:          name,
+		Prefix:        prefix,
+		KeyHash:       utils.HashToken(key),
+		Scopes:        scopes,
+		TransferLimit: req.TransferLimit,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var active int64
+		if err := tx.Model(&models.UserAPIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Count(&active).Error; err != nil {
+			return errors.New("database error")
+		}
+		if active >= maxAPIKeysPerUser {
+			return fmt.Errorf("at most %d api keys allowed", maxAPIKeysPerUser)
+		}
+
+		if err := tx.Create(&apiKey).Error; err != nil {
+			return errors.New("failed to create api key")
+		}
+
+		return s.guard.Record(tx, "api_key_created", &userID, normalizeEmail(user.Email), ip, "key "+prefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserAPIKeyCreatedResponse{Key: key, APIKey: apiKey}, nil
+}
+
+// List returns the user's keys, newest first, including revoked ones
+func (s *APIKeyService) List(userID uint) (*models.UserAPIKeyListResponse, error) {
+	var keys []models.UserAPIKey
+	if err := s.db.Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, errors.New("failed to get api keys")
+	}
+
+	return &models.UserAPIKeyListResponse{
+		APIKeys: keys,
+		Count:   len(keys),
+	}, nil
+}
+
+// Revoke stops one of the user's keys from working
+func (s *APIKeyService) Revoke(userID, keyID uint, ip string) error {
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var apiKey models.UserAPIKey
+		if err := tx.Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).First(&apiKey).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("api key not found")
+			}
+			return errors.New("database error")
+		}
+
+		result := tx.Model(&models.UserAPIKey{}).
+			Where("id = ? AND revoked_at IS NULL", apiKey.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return errors.New("failed to revoke api key")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("api key not found")
+		}
+
+		return s.guard.Record(tx, "api_key_revoked", &userID, normalizeEmail(user.Email), ip, "key "+apiKey.Prefix)
+	})
+}
+
+// revokeAPIKeys stops every key of the user from working and records it with
+// reason. It is called where a password is replaced, so a key minted by
+// whoever had the old password does not outlive it.
+func revokeAPIKeys(tx *gorm.DB, guard *LoginGuard, user *models.User, ip, reason string, now time.Time) error {
+	result := tx.Model(&models.UserAPIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return errors.New("failed to revoke api keys")
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	detail := fmt.Sprintf("%d keys: %s", result.RowsAffected, reason)
+	return guard.Record(tx, "api_key_revoked", &user.ID, normalizeEmail(user.Email), ip, detail)
+}
+
+// Authenticate resolves an active key and its owner from a raw key used from ip
+func (s *APIKeyService) Authenticate(key, ip string) (*models.UserAPIKey, *models.User, error) {
+	rest := strings.TrimPrefix(key, userKeyPrefix)
+	prefix, _, found := strings.Cut(rest, "_")
+	if !found || rest == key {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	var apiKey models.UserAPIKey
+	if err := s.db.Where("prefix = ? AND revoked_at IS NULL", prefix).First(&apiKey).Error; err != nil {
+		return nil, nil, errors.New("invalid api key")
+	}
+	if !utils.CheckTokenHash(key, apiKey.KeyHash) {
+		return nil, nil, errors.New("invalid api key")
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ? AND closed_at IS NULL", apiKey.UserID).First(&user).Error; err != nil {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	if apiKey.LastUsedAt == nil || apiKey.LastUsedIP != ip || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		s.db.Model(&apiKey).Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip})
+	}
+	return &apiKey, &user, nil
+}
+
+// HasScope reports whether apiKey grants scope
+func HasScope(apiKey *models.UserAPIKey, scope string) bool {
+	return containsScope(apiKey.Scopes, scope)
+}
+
+// checkAPIKeyLimit fails when sending amount more points would take the key
+// past its transfer limit. It must run inside the transfer's transaction.
+func checkAPIKeyLimit(tx *gorm.DB, keyID uint, amount uint, now time.Time) error {
+	var apiKey models.UserAPIKey
+	if err := tx.First(&apiKey, keyID).Error; err != nil {
+		return errors.New("database error")
+	}
+	if !containsScope(apiKey.Scopes, ScopeTransfer) {
+		return errors.New("api key cannot transfer")
+	}
+
+	var sent uint
+	if err := tx.Model(&models.Transfer{}).
+		Where("api_key_id = ? AND type = ? AND created_at > ?", keyID, "transfer", now.Add(-apiKeyLimitWindow)).
+		Select("COALESCE(SUM(amount), 0)").Scan(&sent).Error; err != nil {
+		return errors.New("database error")
+	}
+	if sent+amount > apiKey.TransferLimit {
+		return errors.New("api key transfer limit exceeded")
+	}
+	return nil
+}
+
+// normalizeScopes checks scopes against the known ones and drops duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	var result []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !containsScope(apiKeyScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !containsScope(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return result, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *APIKeyService) user(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
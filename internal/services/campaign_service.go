@@ -0,0 +1,381 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Campaign types, each paid out by a different trigger
+const (
+	CampaignSignup        = "signup"
+	CampaignReferral      = "referral"
+	CampaignBirthday      = "birthday"
+	CampaignFirstTransfer = "first_transfer"
+)
+
+type CampaignService struct {
+	db     *gorm.DB
+	ledger *PointLedger
+}
+
+func NewCampaignService(db *gorm.DB, ledger *PointLedger) *CampaignService {
+	return &CampaignService{db: db, ledger: ledger}
+}
+
+// Seed sets up the default welcome campaign and the initial campaign account
+// funds the first time the server starts, so new users keep receiving a
+// sign-up bonus out of the box.
+func (s *CampaignService) Seed(signupBonus uint, initialFunds uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var account models.CampaignAccount
+		if err := tx.FirstOrCreate(&account, models.CampaignAccount{ID: 1}).Error; err != nil {
+			return err
+		}
+
+		var fundings int64
+		if err := tx.Model(&models.CampaignFunding{}).Count(&fundings).Error; err != nil {
+			return err
+		}
+		if fundings == 0 && initialFunds > 0 {
+			if err := s.fund(tx, initialFunds, nil, "Initial funding"); err != nil {
+				return err
+			}
+		}
+
+		var campaigns int64
+		if err := tx.Model(&models.Campaign{}).Count(&campaigns).Error; err != nil {
+			return err
+		}
+		if campaigns == 0 && signupBonus > 0 {
+			welcome := models.Campaign{
+				Name:         "Welcome bonus",
+				Type:         CampaignSignup,
+				RewardAmount: signupBonus,
+				PerUserCap:   1,
+				Active:       true,
+			}
+			return tx.Create(&welcome).Error
+		}
+
+		return nil
+	})
+}
+
+// Reward pays out every active campaign of the given type the user is still
+// eligible for and returns the total number of points awarded. Campaigns that
+// are out of budget, or that the campaign account can no longer cover, are
+// skipped. It must run inside tx.
+func (s *CampaignService) Reward(tx *gorm.DB, campaignType string, userID uint, now time.Time) (uint, error) {
+	var campaigns []models.Campaign
+	if err := tx.Where("type = ? AND active = ?", campaignType, true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("id").
+		Find(&campaigns).Error; err != nil {
+		return 0, errors.New("failed to load campaigns")
+	}
+
+	var total uint
+	for _, campaign := range campaigns {
+		if campaign.Budget > 0 && campaign.Spent+campaign.RewardAmount > campaign.Budget {
+			continue
+		}
+
+		// Birthday caps reset every year, the others apply for the campaign's lifetime
+		rewards := tx.Model(&models.CampaignReward{}).Where("campaign_id = ? AND user_id = ?", campaign.ID, userID)
+		if campaign.Type == CampaignBirthday {
+			rewards = rewards.Where("created_at >= ?", time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()))
+		}
+		var received int64
+		if err := rewards.Count(&received).Error; err != nil {
+			return 0, errors.New("failed to check campaign rewards")
+		}
+		if uint(received) >= campaign.PerUserCap {
+			continue
+		}
+
+		paid, err := s.payout(tx, campaign, userID)
+		if err != nil {
+			return 0, err
+		}
+		if !paid {
+			log.Printf("Campaign %d reward for user %d skipped: budget or campaign account exhausted", campaign.ID, userID)
+			continue
+		}
+		total += campaign.RewardAmount
+	}
+
+	return total, nil
+}
+
+// payout moves one reward from the campaign account to the user. It reports
+// false without error when the campaign budget or the account is exhausted.
+func (s *CampaignService) payout(tx *gorm.DB, campaign models.Campaign, userID uint) (bool, error) {
+	// Guard the budget in SQL as well in case of concurrent payouts
+	spend := tx.Model(&models.Campaign{}).Where("id = ?", campaign.ID)
+	if campaign.Budget > 0 {
+		spend = spend.Where("spent + ? <= budget", campaign.RewardAmount)
+	}
+	result := spend.Update("spent", gorm.Expr("spent + ?", campaign.RewardAmount))
+	if result.Error != nil {
+		return false, errors.New("failed to update campaign budget")
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	result = tx.Model(&models.CampaignAccount{}).
+		Where("id = ? AND balance >= ?", 1, campaign.RewardAmount).
+		Update("balance", gorm.Expr("balance - ?", campaign.RewardAmount))
+	if result.Error != nil {
+		return false, errors.New("failed to debit campaign account")
+	}
+	if result.RowsAffected == 0 {
+		// Give the budget back, nothing was paid
+		if err := tx.Model(&models.Campaign{}).Where("id = ?", campaign.ID).
+			Update("spent", gorm.Expr("spent - ?", campaign.RewardAmount)).Error; err != nil {
+			return false, errors.New("failed to update campaign budget")
+		}
+		return false, nil
+	}
+
+	if err := s.ledger.Credit(tx, userID, campaign.RewardAmount, "campaign"); err != nil {
+		return false, err
+	}
+
+	entry := models.Transfer{
+		ToUserID: &userID,
+		Amount:   campaign.RewardAmount,
+		Message:  campaign.Name,
+		Type:     "bonus",
+		Status:   "completed",
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return false, errors.New("failed to create transfer record")
+	}
+
+	reward := models.CampaignReward{
+		CampaignID: campaign.ID,
+		UserID:     userID,
+		TransferID: entry.ID,
+		Amount:     campaign.RewardAmount,
+	}
+	if err := tx.Create(&reward).Error; err != nil {
+		return false, errors.New("failed to record campaign reward")
+	}
+
+	return true, nil
+}
+
+// RewardBirthdays pays birthday campaigns to every user born on now's day and
+// month. Users born on 29 February are rewarded on 28 February in other years.
+func (s *CampaignService) RewardBirthdays(now time.Time) (int, error) {
+	days := []string{now.Format("01-02")}
+	if now.Month() == time.February && now.Day() == 28 && !isLeapYear(now.Year()) {
+		days = append(days, "02-29")
+	}
+
+	// Dates of birth are encrypted, so they are matched here rather than in SQL
+	var users []models.User
+	var birthdays []uint
+	if err := s.db.Select("id, dob").
+		Where("dob IS NOT NULL AND dob <> ''").
+		FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				if user.DOB.IsZero() {
+					continue
+				}
+				for _, day := range days {
+					if user.DOB.Format("01-02") == day {
+						birthdays = append(birthdays, user.ID)
+					}
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		return 0, errors.New("failed to load birthday users")
+	}
+
+	rewarded := 0
+	for _, userID := range birthdays {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			awarded, err := s.Reward(tx, CampaignBirthday, userID, now)
+			if err == nil && awarded > 0 {
+				rewarded++
+			}
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to reward birthday for user %d: %v", userID, err)
+		}
+	}
+
+	return rewarded, nil
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+func (s *CampaignService) CreateCampaign(req models.CreateCampaignRequest) (*models.Campaign, error) {
+	if !isCampaignType(req.Type) {
+		return nil, errors.New("invalid campaign type")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+
+	perUserCap := req.PerUserCap
+	if perUserCap == 0 {
+		perUserCap = 1
+	}
+
+	campaign := models.Campaign{
+		Name:         req.Name,
+		Type:         req.Type,
+		RewardAmount: req.RewardAmount,
+		Budget:       req.Budget,
+		PerUserCap:   perUserCap,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		Active:       true,
+	}
+	if err := s.db.Create(&campaign).Error; err != nil {
+		return nil, errors.New("failed to create campaign")
+	}
+
+	return &campaign, nil
+}
+
+func (s *CampaignService) UpdateCampaign(campaignID uint, req models.UpdateCampaignRequest) (*models.Campaign, error) {
+	var campaign models.Campaign
+	if err := s.db.First(&campaign, campaignID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("campaign not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.RewardAmount != nil {
+		if *req.RewardAmount == 0 {
+			return nil, errors.New("reward_amount must be positive")
+		}
+		updates["reward_amount"] = *req.RewardAmount
+	}
+	if req.Budget != nil {
+		updates["budget"] = *req.Budget
+	}
+	if req.PerUserCap != nil {
+		updates["per_user_cap"] = *req.PerUserCap
+	}
+	if req.StartsAt != nil {
+		updates["starts_at"] = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		updates["ends_at"] = *req.EndsAt
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if err := s.db.Model(&campaign).Updates(updates).Error; err != nil {
+		return nil, errors.New("failed to update campaign")
+	}
+
+	if err := s.db.First(&campaign, campaignID).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+
+	return &campaign, nil
+}
+
+func (s *CampaignService) ListCampaigns() (*models.CampaignListResponse, error) {
+	var campaigns []models.Campaign
+	if err := s.db.Order("created_at DESC").Find(&campaigns).Error; err != nil {
+		return nil, errors.New("failed to get campaigns")
+	}
+
+	return &models.CampaignListResponse{
+		Campaigns: campaigns,
+		Count:     len(campaigns),
+	}, nil
+}
+
+func (s *CampaignService) GetAccount() (*models.CampaignAccountResponse, error) {
+	var account models.CampaignAccount
+	if err := s.db.First(&account, 1).Error; err != nil {
+		return nil, errors.New("failed to get campaign account")
+	}
+
+	var fundings []models.CampaignFunding
+	if err := s.db.Order("created_at DESC").Limit(50).Find(&fundings).Error; err != nil {
+		return nil, errors.New("failed to get campaign account fundings")
+	}
+
+	return &models.CampaignAccountResponse{
+		Balance:  account.Balance,
+		Fundings: fundings,
+	}, nil
+}
+
+func (s *CampaignService) FundAccount(adminID uint, req models.FundCampaignAccountRequest) (*models.CampaignAccountResponse, error) {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.fund(tx, req.Amount, &adminID, req.Note)
+	}); err != nil {
+		return nil, errors.New("failed to fund campaign account")
+	}
+
+	return s.GetAccount()
+}
+
+// Withdraw takes points out of the campaign account for a payout made outside a campaign
+func (s *CampaignService) Withdraw(tx *gorm.DB, amount uint) error {
+	result := tx.Model(&models.CampaignAccount{}).
+		Where("id = ? AND balance >= ?", 1, amount).
+		Update("balance", gorm.Expr("balance - ?", amount))
+	if result.Error != nil {
+		return errors.New("failed to debit campaign account")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("insufficient campaign funds")
+	}
+	return nil
+}
+
+// Return puts withdrawn points that were never paid out back into the campaign account
+func (s *CampaignService) Return(tx *gorm.DB, amount uint, note string) error {
+	if err := s.fund(tx, amount, nil, note); err != nil {
+		return errors.New("failed to credit campaign account")
+	}
+	return nil
+}
+
+func (s *CampaignService) fund(tx *gorm.DB, amount uint, fundedBy *uint, note string) error {
+	if err := tx.Model(&models.CampaignAccount{}).Where("id = ?", 1).
+		Update("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
+		return err
+	}
+
+	funding := models.CampaignFunding{
+		Amount:   amount,
+		FundedBy: fundedBy,
+		Note:     note,
+	}
+	return tx.Create(&funding).Error
+}
+
+func isCampaignType(campaignType string) bool {
+	switch campaignType {
+	case CampaignSignup, CampaignReferral, CampaignBirthday, CampaignFirstTransfer:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Most units of an item one order may hold
+const maxRedeemQuantity = 100
+
+// Most points one order may cost, like a voucher batch; point columns are
+// signed 64-bit in SQLite, so totals near math.MaxUint would wrap there
+const maxOrderTotal = math.MaxUint32
+
+// Statuses an order may move to from each status. Orders only move forward;
+// fulfilled and cancelled orders are final.
+var orderTransitions = map[string][]string{
+	"pending": {"shipped", "cancelled"},
+	"shipped": {"fulfilled", "cancelled"},
+}
+
+type CatalogService struct {
+	db     *gorm.DB
+	ledger *PointLedger
+}
+
+func NewCatalogService(db *gorm.DB, ledger *PointLedger) *CatalogService {
+	return &CatalogService{db: db, ledger: ledger}
+}
+
+func (s *CatalogService) ListItems(includeInactive bool) (*models.CatalogResponse, error) {
+	query := s.db.Order("point_price, id")
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+
+	var items []models.CatalogItem
+	if err := query.Find(&items).Error; err != nil {
+		return nil, errors.New("failed to get catalog")
+	}
+
+	return &models.CatalogResponse{
+		Items: items,
+		Count: len(items),
+	}, nil
+}
+
+func (s *CatalogService) CreateItem(req models.CreateCatalogItemRequest) (*models.CatalogItem, error) {
+	item := models.CatalogItem{
+		Name:         req.Name,
+		Description:  req.Description,
+		PointPrice:   req.PointPrice,
+		Stock:        req.Stock,
+		PerUserLimit: req.PerUserLimit,
+		Active:       true,
+	}
+	if err := s.db.Create(&item).Error; err != nil {
+		return nil, errors.New("failed to create catalog item")
+	}
+
+	return &item, nil
+}
+
+func (s *CatalogService) UpdateItem(itemID uint, req models.UpdateCatalogItemRequest) (*models.CatalogItem, error) {
+	var item models.CatalogItem
+	if err := s.db.First(&item, itemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("item not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.PointPrice != nil {
+		if *req.PointPrice == 0 {
+			return nil, errors.New("point_price must be positive")
+		}
+		updates["point_price"] = *req.PointPrice
+	}
+	if req.Stock != nil {
+		updates["stock"] = *req.Stock
+	}
+	if req.PerUserLimit != nil {
+		updates["per_user_limit"] = *req.PerUserLimit
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if err := s.db.Model(&item).Updates(updates).Error; err != nil {
+		return nil, errors.New("failed to update catalog item")
+	}
+
+	if err := s.db.First(&item, itemID).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+
+	return &item, nil
+}
+
+// Redeem debits the user's points and reserves stock for a new order in one transaction
+func (s *CatalogService) Redeem(userID, itemID uint, quantity uint) (*models.RedemptionOrder, error) {
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity > maxRedeemQuantity {
+		return nil, fmt.Errorf("quantity must be at most %d", maxRedeemQuantity)
+	}
+
+	var order models.RedemptionOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var item models.CatalogItem
+		if err := tx.Where("id = ? AND active = ?", itemID, true).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("item not found")
+			}
+			return errors.New("database error")
+		}
+
+		if item.PerUserLimit > 0 {
+			var held uint
+			if err := tx.Model(&models.RedemptionOrder{}).
+				Where("user_id = ? AND item_id = ? AND status <> ?", userID, itemID, "cancelled").
+				Select("COALESCE(SUM(quantity), 0)").
+				Scan(&held).Error; err != nil {
+				return errors.New("database error")
+			}
+			if held+quantity > item.PerUserLimit {
+				return errors.New("per-user limit reached")
+			}
+		}
+
+		// Reserve stock only if enough is left, so concurrent redemptions cannot oversell
+		result := tx.Model(&models.CatalogItem{}).
+			Where("id = ? AND stock >= ?", itemID, quantity).
+			Update("stock", gorm.Expr("stock - ?", quantity))
+		if result.Error != nil {
+			return errors.New("failed to reserve stock")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("out of stock")
+		}
+
+		// Checked by division, since the product itself could wrap around
+		if quantity > maxOrderTotal/item.PointPrice {
+			return errors.New("order total too large")
+		}
+		points := item.PointPrice * quantity
+		if err := s.ledger.Debit(tx, userID, points); err != nil {
+			return err
+		}
+
+		entry := models.Transfer{
+			FromUserID: &userID,
+			Amount:     points,
+			Message:    item.Name,
+			Type:       "redemption",
+			Status:     "completed",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		order = models.RedemptionOrder{
+			UserID:      userID,
+			ItemID:      itemID,
+			Item:        item,
+			Quantity:    quantity,
+			PointsSpent: points,
+			Status:      "pending",
+			TransferID:  entry.ID,
+		}
+		if err := tx.Omit("Item").Create(&order).Error; err != nil {
+			return errors.New("failed to create order")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (s *CatalogService) ListOrders(userID uint) (*models.OrderListResponse, error) {
+	var orders []models.RedemptionOrder
+	if err := s.db.Preload("Item").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&orders).Error; err != nil {
+		return nil, errors.New("failed to get orders")
+	}
+
+	return &models.OrderListResponse{
+		Orders: orders,
+		Count:  len(orders),
+	}, nil
+}
+
+func (s *CatalogService) ListAllOrders(status string) (*models.OrderListResponse, error) {
+	query := s.db.Preload("Item").Order("created_at DESC").Limit(200)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.RedemptionOrder
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, errors.New("failed to get orders")
+	}
+
+	return &models.OrderListResponse{
+		Orders: orders,
+		Count:  len(orders),
+	}, nil
+}
+
+// CancelOrder lets a user cancel their own order while it is still pending
+func (s *CatalogService) CancelOrder(userID, orderID uint) (*models.RedemptionOrder, error) {
+	return s.setStatus(orderID, &userID, "cancelled")
+}
+
+// UpdateOrderStatus moves an order through fulfilment on behalf of an admin
+func (s *CatalogService) UpdateOrderStatus(orderID uint, status string) (*models.RedemptionOrder, error) {
+	switch status {
+	case "shipped", "fulfilled", "cancelled":
+	default:
+		return nil, errors.New("invalid order status")
+	}
+	return s.setStatus(orderID, nil, status)
+}
+
+// setStatus changes an order's status along orderTransitions. Cancelling
+// returns the points and the stock; owners (userID set) may only cancel
+// orders that have not shipped.
+func (s *CatalogService) setStatus(orderID uint, userID *uint, status string) (*models.RedemptionOrder, error) {
+	var order models.RedemptionOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Preload("Item")
+		if userID != nil {
+			query = query.Where("user_id = ?", *userID)
+		}
+		if err := query.First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("order not found")
+			}
+			return errors.New("database error")
+		}
+
+		if order.Status == "cancelled" {
+			return errors.New("order already cancelled")
+		}
+		if userID != nil && order.Status != "pending" {
+			return errors.New("order can no longer be cancelled")
+		}
+		allowed := false
+		for _, next := range orderTransitions[order.Status] {
+			allowed = allowed || next == status
+		}
+		if !allowed {
+			return fmt.Errorf("order cannot move from %s to %s", order.Status, status)
+		}
+
+		// Only move from the status just read, so a concurrent change cannot
+		// be overwritten and a cancellation cannot refund twice
+		now := time.Now()
+		updates := map[string]interface{}{"status": status}
+		if status == "cancelled" {
+			updates["cancelled_at"] = now
+		}
+		result := tx.Model(&models.RedemptionOrder{}).
+			Where("id = ? AND status = ?", order.ID, order.Status).
+			Updates(updates)
+		if result.Error != nil {
+			return errors.New("failed to update order")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("order was changed, try again")
+		}
+		order.Status = status
+		if status != "cancelled" {
+			return nil
+		}
+		order.CancelledAt = &now
+
+		if err := tx.Model(&models.CatalogItem{}).Where("id = ?", order.ItemID).
+			Update("stock", gorm.Expr("stock + ?", order.Quantity)).Error; err != nil {
+			return errors.New("failed to restock item")
+		}
+
+		if err := s.ledger.Credit(tx, order.UserID, order.PointsSpent, "refund"); err != nil {
+			return err
+		}
+
+		entry := models.Transfer{
+			ToUserID: &order.UserID,
+			Amount:   order.PointsSpent,
+			Message:  "Cancelled order: " + order.Item.Name,
+			Type:     "refund",
+			Status:   "completed",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
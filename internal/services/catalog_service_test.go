@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fiber-api/internal/models"
+	"math"
+	"testing"
+)
+
+func TestUpdateOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  string
+	}{
+		{"pending", "shipped", ""},
+		{"shipped", "fulfilled", ""},
+		{"pending", "cancelled", ""},
+		{"shipped", "cancelled", ""},
+		{"pending", "fulfilled", "order cannot move from pending to fulfilled"},
+		{"shipped", "shipped", "order cannot move from shipped to shipped"},
+		{"fulfilled", "cancelled", "order cannot move from fulfilled to cancelled"},
+		{"fulfilled", "shipped", "order cannot move from fulfilled to shipped"},
+		{"cancelled", "shipped", "order already cancelled"},
+		{"cancelled", "cancelled", "order already cancelled"},
+		{"shipped", "pending", "invalid order status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			db := newTestDB(t)
+			catalog := NewCatalogService(db, NewPointLedger(0))
+			user := newTestUser(t, db, "jane@example.com")
+
+			item := models.CatalogItem{Name: "Mug", PointPrice: 50, Stock: 3, Active: true}
+			if err := db.Create(&item).Error; err != nil {
+				t.Fatalf("create item: %v", err)
+			}
+			order := models.RedemptionOrder{UserID: user.ID, ItemID: item.ID, Quantity: 2, PointsSpent: 100, Status: tt.from}
+			if err := db.Omit("Item").Create(&order).Error; err != nil {
+				t.Fatalf("create order: %v", err)
+			}
+
+			updated, err := catalog.UpdateOrderStatus(order.ID, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateOrderStatus: %v", err)
+			}
+			if updated.Status != tt.to {
+				t.Errorf("status = %s, want %s", updated.Status, tt.to)
+			}
+
+			// Only a cancellation returns the stock
+			wantStock := uint(3)
+			if tt.to == "cancelled" {
+				wantStock = 5
+			}
+			var stock uint
+			db.Model(&models.CatalogItem{}).Where("id = ?", item.ID).Select("stock").Scan(&stock)
+			if stock != wantStock {
+				t.Errorf("stock = %d, want %d", stock, wantStock)
+			}
+		})
+	}
+}
+
+func TestRedeemRejectsOverflowingQuantity(t *testing.T) {
+	db := newTestDB(t)
+	catalog := NewCatalogService(db, NewPointLedger(0))
+	user := newTestUser(t, db, "jane@example.com")
+
+	pricey := models.CatalogItem{Name: "Mug", PointPrice: math.MaxUint32 / 50, Stock: 1000, Active: true}
+	huge := models.CatalogItem{Name: "Yacht", PointPrice: math.MaxUint / 50, Stock: 1000, Active: true}
+	for _, item := range []*models.CatalogItem{&pricey, &huge} {
+		if err := db.Create(item).Error; err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		itemID   uint
+		quantity uint
+		wantErr  string
+	}{
+		{"above the maximum", pricey.ID, maxRedeemQuantity + 1, "quantity must be at most 100"},
+		{"total above the cap", pricey.ID, 51, "order total too large"},
+		{"price times quantity wraps", huge.ID, 51, "order total too large"},
+		{"within bounds but unaffordable", pricey.ID, 50, "insufficient points"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := catalog.Redeem(user.ID, tt.itemID, tt.quantity); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/mail"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerificationLimits configures EmailVerificationService
+type EmailVerificationLimits struct {
+	TTL            time.Duration // How long a verification link works
+	ResendCooldown time.Duration // Minimum time between two emails to one user
+	MaxPerDay      int           // Emails to one user in 24 hours, 0 for no limit
+}
+
+// EmailVerificationService proves that users own the email address they
+// registered with by emailing them a single-use link
+type EmailVerificationService struct {
+	db        *gorm.DB
+	mailer    mail.Mailer
+	verifyURL string
+	limits    EmailVerificationLimits
+}
+
+func NewEmailVerificationService(db *gorm.DB, mailer mail.Mailer, verifyURL string, limits EmailVerificationLimits) *EmailVerificationService {
+	return &EmailVerificationService{db: db, mailer: mailer, verifyURL: verifyURL, limits: limits}
+}
+
+// Send emails a verification link to the user, as on registration
+func (s *EmailVerificationService) Send(user *models.User) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return errors.New("failed to generate token")
+	}
+
+	// Earlier links keep working until they expire; they all verify the same address
+	record := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   "email_verification",
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.limits.TTL),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return errors.New("failed to create token")
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"Please confirm that %s is your email address by opening this link:\n\n"+
+		"%s?token=%s\n\n"+
+		"The link expires in %d hours. If you did not create an account, you can ignore this email.\n",
+		user.FirstName, user.Email, s.verifyURL, token, int(s.limits.TTL.Hours()))
+	if err := s.mailer.Send(user.Email, "Verify your email address", body); err != nil {
+		return errors.New("failed to send verification email")
+	}
+
+	return nil
+}
+
+// Resend emails a new verification link, at most once per cooldown and
+// MaxPerDay times a day
+func (s *EmailVerificationService) Resend(userID uint) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return errors.New("database error")
+	}
+	if user.EmailVerified {
+		return errors.New("email already verified")
+	}
+
+	now := time.Now()
+	var sent []models.UserToken
+	if err := s.db.Where("user_id = ? AND purpose = ? AND created_at > ?", userID, "email_verification", now.Add(-24*time.Hour)).
+		Order("created_at DESC").
+		Find(&sent).Error; err != nil {
+		return errors.New("database error")
+	}
+	if len(sent) > 0 && now.Sub(sent[0].CreatedAt) < s.limits.ResendCooldown {
+		return errors.New("verification email recently sent")
+	}
+	if s.limits.MaxPerDay > 0 && len(sent) >= s.limits.MaxPerDay {
+		return errors.New("too many verification emails")
+	}
+
+	return s.Send(&user)
+}
+
+// Verify marks the email address of the token's user as verified
+func (s *EmailVerificationService) Verify(token string) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var record models.UserToken
+		if err := tx.Where("token_hash = ? AND purpose = ?", utils.HashToken(token), "email_verification").
+			First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid or expired token")
+			}
+			return errors.New("database error")
+		}
+		if record.UsedAt != nil || !now.Before(record.ExpiresAt) {
+			return errors.New("invalid or expired token")
+		}
+
+		if err := tx.First(&user, record.UserID).Error; err != nil {
+			return errors.New("database error")
+		}
+		if user.EmailVerified {
+			return errors.New("email already verified")
+		}
+
+		// Every outstanding link is used up with the first one followed
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, "email_verification").
+			Update("used_at", now).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified = ?", user.ID, false).
+			Updates(map[string]interface{}{
+				"email_verified":    true,
+				"email_verified_at": now,
+			})
+		if result.Error != nil {
+			return errors.New("failed to update user")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("email already verified")
+		}
+
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
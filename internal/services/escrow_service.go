@@ -0,0 +1,282 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EscrowService struct {
+	db       *gorm.DB
+	ledger   *PointLedger
+	pins     *PINService
+	timeout  time.Duration
+	maxHours int
+}
+
+func NewEscrowService(db *gorm.DB, ledger *PointLedger, pins *PINService, timeout time.Duration, maxHours int) *EscrowService {
+	return &EscrowService{db: db, ledger: ledger, pins: pins, timeout: timeout, maxHours: maxHours}
+}
+
+// Create takes the points from the sender and holds them for the recipient
+// until the sender releases them or the escrow times out
+func (s *EscrowService) Create(senderID uint, req models.CreateEscrowRequest) (*models.Escrow, error) {
+	if req.ExpiresInHours > s.maxHours {
+		return nil, fmt.Errorf("expires_in_hours must be at most %d", s.maxHours)
+	}
+	if err := s.pins.Authorize(senderID, req.Amount, req.PIN); err != nil {
+		return nil, err
+	}
+
+	currency := NormalizeCurrency(req.Currency)
+
+	var escrow models.Escrow
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := RequireActive(tx, currency); err != nil {
+			return err
+		}
+
+		var recipient models.User
+		if err := tx.Where("lbk_code = ? AND closed_at IS NULL", req.ToLBKCode).First(&recipient).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("recipient user not found")
+			}
+			return errors.New("database error")
+		}
+		if recipient.ID == senderID {
+			return errors.New("cannot transfer points to yourself")
+		}
+
+		if err := s.ledger.DebitCurrency(tx, senderID, currency, req.Amount); err != nil {
+			return err
+		}
+
+		transfer := models.Transfer{
+			FromUserID: &senderID,
+			ToUserID:   &recipient.ID,
+			Amount:     req.Amount,
+			Currency:   currency,
+			Message:    req.Message,
+			Type:       "escrow",
+			Status:     "held",
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		timeout := s.timeout
+		if req.ExpiresInHours > 0 {
+			timeout = time.Duration(req.ExpiresInHours) * time.Hour
+		}
+
+		escrow = models.Escrow{
+			TransferID:  transfer.ID,
+			SenderID:    senderID,
+			RecipientID: recipient.ID,
+			Amount:      req.Amount,
+			Currency:    currency,
+			Status:      "held",
+			ExpiresAt:   time.Now().Add(timeout),
+		}
+		if err := tx.Create(&escrow).Error; err != nil {
+			return errors.New("failed to create escrow")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &escrow, nil
+}
+
+// List returns the escrows the user is sending or receiving
+func (s *EscrowService) List(userID uint) (*models.EscrowListResponse, error) {
+	var escrows []models.Escrow
+	if err := s.db.Where("sender_id = ? OR recipient_id = ?", userID, userID).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&escrows).Error; err != nil {
+		return nil, errors.New("failed to get escrows")
+	}
+
+	return &models.EscrowListResponse{
+		Escrows: escrows,
+		Count:   len(escrows),
+	}, nil
+}
+
+func (s *EscrowService) ListAll(status string) (*models.EscrowListResponse, error) {
+	query := s.db.Order("created_at DESC").Limit(200)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var escrows []models.Escrow
+	if err := query.Find(&escrows).Error; err != nil {
+		return nil, errors.New("failed to get escrows")
+	}
+
+	return &models.EscrowListResponse{
+		Escrows: escrows,
+		Count:   len(escrows),
+	}, nil
+}
+
+// Release pays the held points to the recipient once the sender confirms delivery.
+// A sender may also release a disputed escrow, giving up the dispute.
+func (s *EscrowService) Release(senderID, escrowID uint) (*models.Escrow, error) {
+	return s.settle(escrowID, func(escrow *models.Escrow) (string, map[string]interface{}, error) {
+		if escrow.SenderID != senderID {
+			return "", nil, errors.New("escrow not found")
+		}
+		return "released", nil, nil
+	}, "held", "disputed")
+}
+
+// Dispute freezes a held escrow until an admin resolves it. Disputed escrows do not time out.
+func (s *EscrowService) Dispute(userID, escrowID uint, reason string) (*models.Escrow, error) {
+	var escrow models.Escrow
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND (sender_id = ? OR recipient_id = ?)", escrowID, userID, userID).
+			First(&escrow).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("escrow not found")
+			}
+			return errors.New("database error")
+		}
+
+		result := tx.Model(&models.Escrow{}).
+			Where("id = ? AND status = ?", escrow.ID, "held").
+			Updates(map[string]interface{}{
+				"status":         "disputed",
+				"disputed_by":    userID,
+				"dispute_reason": reason,
+			})
+		if result.Error != nil {
+			return errors.New("failed to dispute escrow")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("escrow is no longer held")
+		}
+
+		if err := tx.Model(&models.Transfer{}).Where("id = ?", escrow.TransferID).
+			Update("status", "disputed").Error; err != nil {
+			return errors.New("failed to update transfer record")
+		}
+
+		escrow.Status = "disputed"
+		escrow.DisputedBy = &userID
+		escrow.DisputeReason = reason
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &escrow, nil
+}
+
+// Resolve settles a disputed escrow on behalf of an admin, either releasing
+// the points to the recipient or refunding them to the sender
+func (s *EscrowService) Resolve(adminID, escrowID uint, req models.ResolveEscrowRequest) (*models.Escrow, error) {
+	var status string
+	switch req.Resolution {
+	case "release":
+		status = "released"
+	case "refund":
+		status = "refunded"
+	default:
+		return nil, errors.New("invalid resolution")
+	}
+
+	return s.settle(escrowID, func(escrow *models.Escrow) (string, map[string]interface{}, error) {
+		return status, map[string]interface{}{
+			"resolved_by":     adminID,
+			"resolution_note": req.Note,
+		}, nil
+	}, "disputed")
+}
+
+// RefundExpired returns the points of every held escrow whose deadline has passed
+func (s *EscrowService) RefundExpired(now time.Time) (int, error) {
+	var escrows []models.Escrow
+	if err := s.db.Where("status = ? AND expires_at <= ?", "held", now).Find(&escrows).Error; err != nil {
+		return 0, errors.New("failed to load expired escrows")
+	}
+
+	refunded := 0
+	for _, escrow := range escrows {
+		_, err := s.settle(escrow.ID, func(*models.Escrow) (string, map[string]interface{}, error) {
+			return "refunded", nil, nil
+		}, "held")
+		if err != nil {
+			log.Printf("Failed to refund escrow %d: %v", escrow.ID, err)
+			continue
+		}
+		refunded++
+	}
+
+	return refunded, nil
+}
+
+// settle closes an escrow that is in one of the from statuses. decide checks
+// the caller may act and picks released or refunded plus any extra columns.
+func (s *EscrowService) settle(escrowID uint, decide func(*models.Escrow) (string, map[string]interface{}, error), from ...string) (*models.Escrow, error) {
+	var escrow models.Escrow
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&escrow, escrowID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("escrow not found")
+			}
+			return errors.New("database error")
+		}
+
+		status, updates, err := decide(&escrow)
+		if err != nil {
+			return err
+		}
+		if updates == nil {
+			updates = map[string]interface{}{}
+		}
+		now := time.Now()
+		updates["status"] = status
+		updates["closed_at"] = now
+
+		// Guard against a concurrent release and refund paying out twice
+		result := tx.Model(&models.Escrow{}).
+			Where("id = ? AND status IN ?", escrow.ID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return errors.New("failed to update escrow")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("escrow is no longer open")
+		}
+
+		payee, source, transferStatus := escrow.RecipientID, "escrow", "completed"
+		if status == "refunded" {
+			payee, source, transferStatus = escrow.SenderID, "refund", "refunded"
+		}
+		if err := s.ledger.CreditCurrency(tx, payee, escrow.Currency, escrow.Amount, source); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Transfer{}).Where("id = ?", escrow.TransferID).
+			Update("status", transferStatus).Error; err != nil {
+			return errors.New("failed to update transfer record")
+		}
+
+		return tx.First(&escrow, escrow.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &escrow, nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rates carry six decimals
+const rateScale = 1000000
+
+type ExchangeService struct {
+	db       *gorm.DB
+	ledger   *PointLedger
+	quoteTTL time.Duration
+}
+
+func NewExchangeService(db *gorm.DB, ledger *PointLedger, quoteTTL time.Duration) *ExchangeService {
+	return &ExchangeService{db: db, ledger: ledger, quoteTTL: quoteTTL}
+}
+
+func (s *ExchangeService) CreateRate(adminID uint, req models.CreateExchangeRateRequest) (*models.ExchangeRate, error) {
+	from := NormalizeCurrency(req.FromCurrency)
+	to := NormalizeCurrency(req.ToCurrency)
+	if from == to {
+		return nil, errors.New("currencies must differ")
+	}
+
+	micros, err := parseRate(req.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range []string{from, to} {
+		var known int64
+		if err := s.db.Model(&models.Currency{}).Where("code = ?", code).Count(&known).Error; err != nil {
+			return nil, errors.New("database error")
+		}
+		if known == 0 {
+			return nil, errors.New("unknown currency")
+		}
+	}
+
+	effectiveFrom := time.Now()
+	if req.EffectiveFrom != nil {
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	rate := models.ExchangeRate{
+		FromCurrency:  from,
+		ToCurrency:    to,
+		Rate:          formatRate(micros),
+		RateMicros:    micros,
+		EffectiveFrom: effectiveFrom,
+		CreatedBy:     &adminID,
+	}
+	if err := s.db.Create(&rate).Error; err != nil {
+		return nil, errors.New("failed to create exchange rate")
+	}
+
+	return &rate, nil
+}
+
+// ListRates returns every rate for admins, or only the ones in effect now
+func (s *ExchangeService) ListRates(currentOnly bool) (*models.ExchangeRateListResponse, error) {
+	var rates []models.ExchangeRate
+	if currentOnly {
+		// The newest rate per pair that has already taken effect
+		now := time.Now()
+		if err := s.db.Where("effective_from <= ?", now).
+			Where("id = (SELECT r.id FROM exchange_rates r WHERE r.from_currency = exchange_rates.from_currency AND r.to_currency = exchange_rates.to_currency AND r.effective_from <= ? ORDER BY r.effective_from DESC, r.id DESC LIMIT 1)", now).
+			Order("from_currency, to_currency").
+			Find(&rates).Error; err != nil {
+			return nil, errors.New("failed to get exchange rates")
+		}
+	} else {
+		if err := s.db.Order("from_currency, to_currency, effective_from DESC").Find(&rates).Error; err != nil {
+			return nil, errors.New("failed to get exchange rates")
+		}
+	}
+
+	return &models.ExchangeRateListResponse{
+		Rates: rates,
+		Count: len(rates),
+	}, nil
+}
+
+// Quote locks the current rate for converting amount points for quoteTTL
+func (s *ExchangeService) Quote(userID uint, req models.ConversionQuoteRequest) (*models.Conversion, error) {
+	from := NormalizeCurrency(req.FromCurrency)
+	to := NormalizeCurrency(req.ToCurrency)
+	if from == to {
+		return nil, errors.New("currencies must differ")
+	}
+	for _, code := range []string{from, to} {
+		if err := RequireActive(s.db, code); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	var rate models.ExchangeRate
+	if err := s.db.Where("from_currency = ? AND to_currency = ? AND effective_from <= ?", from, to, now).
+		Order("effective_from DESC, id DESC").
+		First(&rate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no exchange rate for these currencies")
+		}
+		return nil, errors.New("database error")
+	}
+
+	toAmount, remainder, err := convert(req.Amount, rate.RateMicros)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateSecureToken(18)
+	if err != nil {
+		return nil, errors.New("failed to create quote")
+	}
+
+	conversion := models.Conversion{
+		Code:            "cq_" + token,
+		UserID:          userID,
+		FromCurrency:    from,
+		ToCurrency:      to,
+		FromAmount:      req.Amount,
+		ToAmount:        toAmount,
+		RateID:          rate.ID,
+		Rate:            rate.Rate,
+		Rounding:        "down",
+		RemainderMicros: remainder,
+		Status:          "quoted",
+		ExpiresAt:       now.Add(s.quoteTTL),
+	}
+	if err := s.db.Create(&conversion).Error; err != nil {
+		return nil, errors.New("failed to create quote")
+	}
+
+	return &conversion, nil
+}
+
+// Execute performs a quoted conversion at its locked rate, moving the points
+// between the user's wallets in one transaction
+func (s *ExchangeService) Execute(userID uint, code string) (*models.Conversion, error) {
+	var conversion models.Conversion
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code = ? AND user_id = ?", code, userID).First(&conversion).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("quote not found")
+			}
+			return errors.New("database error")
+		}
+
+		if conversion.Status != "quoted" {
+			return errors.New("quote is no longer open")
+		}
+		now := time.Now()
+		if now.After(conversion.ExpiresAt) {
+			return errors.New("quote expired")
+		}
+		for _, code := range []string{conversion.FromCurrency, conversion.ToCurrency} {
+			if err := RequireActive(tx, code); err != nil {
+				return err
+			}
+		}
+
+		if err := s.ledger.DebitCurrency(tx, userID, conversion.FromCurrency, conversion.FromAmount); err != nil {
+			return err
+		}
+		if err := s.ledger.CreditCurrency(tx, userID, conversion.ToCurrency, conversion.ToAmount, "conversion"); err != nil {
+			return err
+		}
+
+		debit := models.Transfer{
+			FromUserID: &userID,
+			Amount:     conversion.FromAmount,
+			Currency:   conversion.FromCurrency,
+			Message:    "Converted to " + conversion.ToCurrency + " at " + conversion.Rate,
+			Type:       "conversion",
+			Status:     "completed",
+		}
+		if err := tx.Create(&debit).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+		credit := models.Transfer{
+			ToUserID: &userID,
+			Amount:   conversion.ToAmount,
+			Currency: conversion.ToCurrency,
+			Message:  "Converted from " + conversion.FromCurrency + " at " + conversion.Rate,
+			Type:     "conversion",
+			Status:   "completed",
+		}
+		if err := tx.Create(&credit).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		// Only the first execution wins if two arrive at once
+		result := tx.Model(&models.Conversion{}).
+			Where("id = ? AND status = ?", conversion.ID, "quoted").
+			Updates(map[string]interface{}{
+				"status":             "executed",
+				"debit_transfer_id":  debit.ID,
+				"credit_transfer_id": credit.ID,
+				"executed_at":        now,
+			})
+		if result.Error != nil {
+			return errors.New("failed to execute conversion")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("quote is no longer open")
+		}
+		conversion.Status = "executed"
+		conversion.DebitTransferID = &debit.ID
+		conversion.CreditTransferID = &credit.ID
+		conversion.ExecutedAt = &now
+
+		return nil
+	})
+	// Record the expiry outside the rolled back transaction
+	if err != nil && err.Error() == "quote expired" {
+		s.db.Model(&models.Conversion{}).Where("id = ? AND status = ?", conversion.ID, "quoted").
+			Update("status", "expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversion, nil
+}
+
+// convert applies a fixed-point rate to amount, rounding down to whole points.
+// It returns the converted amount and the dropped fraction in micro-points.
+func convert(amount uint, rateMicros uint64) (uint, uint64, error) {
+	product := new(big.Int).Mul(new(big.Int).SetUint64(uint64(amount)), new(big.Int).SetUint64(rateMicros))
+	whole, remainder := new(big.Int).QuoRem(product, big.NewInt(rateScale), new(big.Int))
+
+	if whole.Sign() == 0 {
+		return 0, 0, errors.New("amount too small to convert")
+	}
+	if !whole.IsUint64() || whole.Uint64() > uint64(^uint32(0)) {
+		return 0, 0, errors.New("amount too large to convert")
+	}
+
+	return uint(whole.Uint64()), remainder.Uint64(), nil
+}
+
+// parseRate reads a positive decimal with up to six places into micro-units
+func parseRate(rate string) (uint64, error) {
+	invalid := errors.New("rate must be a positive decimal with up to 6 places")
+
+	whole, frac, _ := strings.Cut(strings.TrimSpace(rate), ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 6 {
+		return 0, invalid
+	}
+	frac += strings.Repeat("0", 6-len(frac))
+
+	w, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil {
+		return 0, invalid
+	}
+	f, err := strconv.ParseUint(frac, 10, 32)
+	if err != nil {
+		return 0, invalid
+	}
+
+	micros := w*rateScale + f
+	if micros == 0 {
+		return 0, invalid
+	}
+	return micros, nil
+}
+
+// formatRate renders micro-units as a decimal without trailing zeros
+func formatRate(micros uint64) string {
+	s := strconv.FormatUint(micros/rateScale, 10)
+	if frac := micros % rateScale; frac > 0 {
+		s += "." + strings.TrimRight(strconv.FormatUint(frac+rateScale, 10)[1:], "0")
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package services
+
+import "testing"
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate    string
+		want    uint64
+		wantErr bool
+	}{
+		{"1", 1000000, false},
+		{"1.5", 1500000, false},
+		{"0.000001", 1, false},
+		{".5", 500000, false},
+		{"2.", 2000000, false},
+		{" 2.25 ", 2250000, false},
+		{"0.100000", 100000, false},
+		{"4294967295.999999", 4294967295999999, false},
+		{"4294967296", 0, true},
+		{"0", 0, true},
+		{"0.000000", 0, true},
+		{"0.0000001", 0, true},
+		{"1.0000001", 0, true},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"+1", 0, true},
+		{"1.-5", 0, true},
+		{"1.2.3", 0, true},
+		{"1e3", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			got, err := parseRate(tt.rate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRate(%q) = %d, want %d", tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRateRoundTrip(t *testing.T) {
+	tests := []struct {
+		micros uint64
+		want   string
+	}{
+		{1000000, "1"},
+		{1500000, "1.5"},
+		{1, "0.000001"},
+		{100000, "0.1"},
+		{1000010, "1.00001"},
+		{4294967295999999, "4294967295.999999"},
+	}
+	for _, tt := range tests {
+		got := formatRate(tt.micros)
+		if got != tt.want {
+			t.Errorf("formatRate(%d) = %q, want %q", tt.micros, got, tt.want)
+		}
+		if parsed, err := parseRate(got); err != nil || parsed != tt.micros {
+			t.Errorf("parseRate(%q) = %d, %v, want %d", got, parsed, err, tt.micros)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	const maxPoints = uint(^uint32(0))
+
+	tests := []struct {
+		name          string
+		amount        uint
+		rateMicros    uint64
+		want          uint
+		wantRemainder uint64
+		wantErr       string
+	}{
+		{"whole rate", 100, 2000000, 200, 0, ""},
+		{"fractional rate", 100, 1500000, 150, 0, ""},
+		{"rounds down", 10, 333333, 3, 333330, ""},
+		{"keeps the dropped fraction", 7, 1250000, 8, 750000, ""},
+		{"smallest rate", 1000000, 1, 1, 0, ""},
+		{"just under a point more", 1999999, 1, 1, 999999, ""},
+		{"just under one point", 3, 333333, 0, 0, "amount too small to convert"},
+		{"smallest rate on one point", 1, 1, 0, 0, "amount too small to convert"},
+		{"largest result", maxPoints, 1000000, maxPoints, 0, ""},
+		{"largest result from a fraction", maxPoints * 2, 500000, maxPoints, 0, ""},
+		{"one point too many", maxPoints, 1000001, 0, 0, "amount too large to convert"},
+		{"beyond 64 bits", maxPoints, 4294967295999999, 0, 0, "amount too large to convert"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, remainder, err := convert(tt.amount, tt.rateMicros)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convert: %v", err)
+			}
+			if got != tt.want || remainder != tt.wantRemainder {
+				t.Errorf("convert(%d, %d) = %d, %d, want %d, %d", tt.amount, tt.rateMicros, got, remainder, tt.want, tt.wantRemainder)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fiber-api/internal/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Longest wait imposed between attempts before an outright lockout
+const maxLoginDelay = time.Minute
+
+// LoginLimits configures LoginGuard
+type LoginLimits struct {
+	DelayAfter         int           // Failures for one email after which every further attempt must wait, doubling each time
+	MaxAccountFailures int           // Failures for one email before it is locked
+	MaxIPFailures      int           // Failures from one IP, across all emails, before it is locked
+	Lockout            time.Duration // Lock duration; failures older than this are forgotten
+}
+
+// ThrottledError is returned by VerifyPassword while the account must wait
+// before another password may be tried
+type ThrottledError struct {
+	Wait time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "too many attempts, try again later"
+}
+
+// LoginGuard slows down and then locks out password guessing, per email and
+// per IP, and records what happened as security events
+type LoginGuard struct {
+	db     *gorm.DB
+	limits LoginLimits
+}
+
+func NewLoginGuard(db *gorm.DB, limits LoginLimits) *LoginGuard {
+	return &LoginGuard{db: db, limits: limits}
+}
+
+// Check reports how long the caller must wait before a login for email from
+// ip may be attempted. Zero means go ahead.
+func (g *LoginGuard) Check(email, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	var throttles []models.LoginThrottle
+	if err := g.db.Where("(kind = ? AND key = ?) OR (kind = ? AND key = ?)", "account", pii.EmailIndex(email), "ip", ip).
+		Find(&throttles).Error; err != nil {
+		return 0, errors.New("database error")
+	}
+
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if w := g.wait(throttle, now); w > wait {
+			wait = w
+		}
+	}
+	return wait, nil
+}
+
+// Fail records a wrong password, or an unknown email, for email from ip
+func (g *LoginGuard) Fail(email, ip string) error {
+	email = normalizeEmail(email)
+	emailIndex := pii.EmailIndex(email)
+	now := time.Now()
+
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		// Attach the account to the events when there is one
+		var userID *uint
+		var ids []uint
+		if err := tx.Model(&models.User{}).Where("email_index = ?", emailIndex).Pluck("id", &ids).Error; err != nil {
+			return errors.New("database error")
+		}
+		if len(ids) > 0 {
+			userID = &ids[0]
+		}
+
+		if err := g.Record(tx, "login_failed", userID, email, ip, ""); err != nil {
+			return err
+		}
+
+		locked, err := g.count(tx, "account", emailIndex, g.limits.MaxAccountFailures, now)
+		if err != nil {
+			return err
+		}
+		if locked {
+			detail := fmt.Sprintf("%d failed logins", g.limits.MaxAccountFailures)
+			if err := g.Record(tx, "account_locked", userID, email, ip, detail); err != nil {
+				return err
+			}
+		}
+
+		locked, err = g.count(tx, "ip", ip, g.limits.MaxIPFailures, now)
+		if err != nil {
+			return err
+		}
+		if locked {
+			detail := fmt.Sprintf("%d failed logins from this IP", g.limits.MaxIPFailures)
+			if err := g.Record(tx, "ip_locked", userID, email, ip, detail); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// VerifyPassword checks a password the user re-enters to confirm a sensitive
+// action from ip. Wrong passwords count towards the same delays and lockouts
+// as failed logins for the user's email, so a stolen token cannot be used to
+// guess the password.
+func (g *LoginGuard) VerifyPassword(user *models.User, password, ip string) error {
+	wait, err := g.Check(user.Email, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &ThrottledError{Wait: wait}
+	}
+
+	if !utils.CheckPassword(password, user.Password) {
+		if err := g.Fail(user.Email, ip); err != nil {
+			return err
+		}
+		return errors.New("invalid password")
+	}
+	return g.Succeed(user.Email)
+}
+
+// Succeed forgets the failed attempts on email after a correct password. The
+// IP's count is kept, or an attacker could reset it by logging into their own account.
+func (g *LoginGuard) Succeed(email string) error {
+	if err := g.db.Where("kind = ? AND key = ?", "account", pii.EmailIndex(email)).
+		Delete(&models.LoginThrottle{}).Error; err != nil {
+		return errors.New("database error")
+	}
+	return nil
+}
+
+// Unlock lifts a lockout on the account, e.g. after its password was reset
+func (g *LoginGuard) Unlock(userID uint, email, reason string) error {
+	email = normalizeEmail(email)
+
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("kind = ? AND key = ?", "account", pii.EmailIndex(email)).Delete(&models.LoginThrottle{})
+		if result.Error != nil {
+			return errors.New("database error")
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return g.Record(tx, "account_unlocked", &userID, email, "", reason)
+	})
+}
+
+// Events lists security events, newest first, optionally filtered by type and email
+func (g *LoginGuard) Events(eventType, email string) (*models.SecurityEventListResponse, error) {
+	query := g.db.Order("created_at DESC").Limit(200)
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+	if email != "" {
+		query = query.Where("email_index = ?", pii.EmailIndex(email))
+	}
+
+	var events []models.SecurityEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, errors.New("failed to get security events")
+	}
+
+	return &models.SecurityEventListResponse{
+		Events: events,
+		Count:  len(events),
+	}, nil
+}
+
+// wait is how long the throttle still blocks attempts at now
+func (g *LoginGuard) wait(throttle models.LoginThrottle, now time.Time) time.Duration {
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return throttle.LockedUntil.Sub(now)
+	}
+	if throttle.LockedUntil != nil || now.Sub(throttle.LastFailureAt) >= g.limits.Lockout {
+		// Lock served or failures forgotten
+		return 0
+	}
+	// Only emails are slowed down; many users may share one IP behind a NAT
+	if throttle.Kind != "account" || throttle.Failures < g.limits.DelayAfter {
+		return 0
+	}
+
+	// 1s after DelayAfter failures, then 2s, 4s, ...
+	delay := maxLoginDelay
+	if shift := throttle.Failures - g.limits.DelayAfter; shift < 6 {
+		delay = time.Second << shift
+	}
+	if next := throttle.LastFailureAt.Add(delay); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// count adds a failure to the throttle for key and locks it once max failures
+// are reached. It reports whether this failure caused the lock.
+func (g *LoginGuard) count(tx *gorm.DB, kind, key string, max int, now time.Time) (bool, error) {
+	var throttle models.LoginThrottle
+	if err := tx.Where(models.LoginThrottle{Kind: kind, Key: key}).FirstOrCreate(&throttle).Error; err != nil {
+		return false, errors.New("database error")
+	}
+
+	// Already locked by a concurrent attempt
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return false, nil
+	}
+
+	// Start over once a lock has been served or the last failure is old
+	if throttle.LockedUntil != nil || now.Sub(throttle.LastFailureAt) >= g.limits.Lockout {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	locked := max > 0 && throttle.Failures >= max
+	if locked {
+		until := now.Add(g.limits.Lockout)
+		throttle.LockedUntil = &until
+	}
+
+	if err := tx.Save(&throttle).Error; err != nil {
+		return false, errors.New("database error")
+	}
+	return locked, nil
+}
+
+// Record stores a security event. Only the blind index of email is kept. Pass
+// a transaction to keep the event only if that transaction commits.
+func (g *LoginGuard) Record(tx *gorm.DB, eventType string, userID *uint, email, ip, detail string) error {
+	event := models.SecurityEvent{
+		Type:       eventType,
+		UserID:     userID,
+		EmailIndex: pii.EmailIndex(email),
+		IP:         ip,
+		Detail:     detail,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return errors.New("failed to record security event")
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	merchantKeyPrefix = "mk_"
+	prefixAlphabet    = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+type MerchantService struct {
+	db        *gorm.DB
+	ledger    *PointLedger
+	pins      *PINService
+	intentTTL time.Duration
+}
+
+func NewMerchantService(db *gorm.DB, ledger *PointLedger, pins *PINService, intentTTL time.Duration) *MerchantService {
+	return &MerchantService{db: db, ledger: ledger, pins: pins, intentTTL: intentTTL}
+}
+
+func (s *MerchantService) CreateMerchant(req models.CreateMerchantRequest) (*models.Merchant, error) {
+	var existing models.Merchant
+	if err := s.db.Where("email = ?", req.Email).First(&existing).Error; err == nil {
+		return nil, errors.New("merchant already exists")
+	}
+
+	merchant := models.Merchant{
+		Name:   req.Name,
+		Email:  req.Email,
+		Status: "active",
+	}
+	if err := s.db.Create(&merchant).Error; err != nil {
+		return nil, errors.New("failed to create merchant")
+	}
+
+	return &merchant, nil
+}
+
+func (s *MerchantService) ListMerchants() (*models.MerchantListResponse, error) {
+	var merchants []models.Merchant
+	if err := s.db.Order("created_at DESC").Find(&merchants).Error; err != nil {
+		return nil, errors.New("failed to get merchants")
+	}
+
+	return &models.MerchantListResponse{
+		Merchants: merchants,
+		Count:     len(merchants),
+	}, nil
+}
+
+// CreateAPIKey issues a new key for the merchant. The returned key is not
+// stored and cannot be shown again.
+func (s *MerchantService) CreateAPIKey(merchantID uint, name string) (*models.APIKeyCreatedResponse, error) {
+	var merchant models.Merchant
+	if err := s.db.First(&merchant, merchantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("merchant not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	prefix, err := utils.RandomString(prefixAlphabet, 8)
+	if err != nil {
+		return nil, errors.New("failed to generate api key")
+	}
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate api key")
+	}
+	key := merchantKeyPrefix + prefix + "_" + secret
+
+	apiKey := models.MerchantAPIKey{
+		MerchantID: merchant.ID,
+		Name:       name,
+		Prefix:     prefix,
+		KeyHash:    utils.HashToken(key),
+	}
+	if err := s.db.Create(&apiKey).Error; err != nil {
+		return nil, errors.New("failed to create api key")
+	}
+
+	return &models.APIKeyCreatedResponse{Key: key, APIKey: apiKey}, nil
+}
+
+func (s *MerchantService) RevokeAPIKey(merchantID, keyID uint) error {
+	result := s.db.Model(&models.MerchantAPIKey{}).
+		Where("id = ? AND merchant_id = ? AND revoked_at IS NULL", keyID, merchantID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.New("failed to revoke api key")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves an active merchant from a raw API key
+func (s *MerchantService) AuthenticateAPIKey(key string) (*models.Merchant, error) {
+	rest := strings.TrimPrefix(key, merchantKeyPrefix)
+	prefix, _, found := strings.Cut(rest, "_")
+	if !found || rest == key {
+		return nil, errors.New("invalid api key")
+	}
+
+	var apiKey models.MerchantAPIKey
+	if err := s.db.Where("prefix = ? AND revoked_at IS NULL", prefix).First(&apiKey).Error; err != nil {
+		return nil, errors.New("invalid api key")
+	}
+	if !utils.CheckTokenHash(key, apiKey.KeyHash) {
+		return nil, errors.New("invalid api key")
+	}
+
+	var merchant models.Merchant
+	if err := s.db.Where("id = ? AND status = ?", apiKey.MerchantID, "active").First(&merchant).Error; err != nil {
+		return nil, errors.New("invalid api key")
+	}
+
+	s.db.Model(&apiKey).Update("last_used_at", time.Now())
+	return &merchant, nil
+}
+
+func (s *MerchantService) CreatePaymentIntent(merchantID uint, req models.CreatePaymentIntentRequest) (*models.PaymentIntent, error) {
+	code, err := utils.GenerateSecureToken(18)
+	if err != nil {
+		return nil, errors.New("failed to create payment intent")
+	}
+
+	intent := models.PaymentIntent{
+		Code:        "pi_" + code,
+		MerchantID:  merchantID,
+		Amount:      req.Amount,
+		Reference:   req.Reference,
+		Description: req.Description,
+		Status:      "requires_confirmation",
+		ExpiresAt:   time.Now().Add(s.intentTTL),
+	}
+	if err := s.db.Create(&intent).Error; err != nil {
+		return nil, errors.New("failed to create payment intent")
+	}
+
+	return &intent, nil
+}
+
+// GetMerchantIntent returns one of the merchant's own intents
+func (s *MerchantService) GetMerchantIntent(merchantID uint, code string) (*models.PaymentIntent, error) {
+	var intent models.PaymentIntent
+	if err := s.db.Where("code = ? AND merchant_id = ?", code, merchantID).First(&intent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment intent not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &intent, nil
+}
+
+func (s *MerchantService) CancelPaymentIntent(merchantID uint, code string) (*models.PaymentIntent, error) {
+	intent, err := s.GetMerchantIntent(merchantID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.Model(&models.PaymentIntent{}).
+		Where("id = ? AND status = ?", intent.ID, "requires_confirmation").
+		Update("status", "cancelled")
+	if result.Error != nil {
+		return nil, errors.New("failed to cancel payment intent")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("payment intent is no longer open")
+	}
+	intent.Status = "cancelled"
+
+	return intent, nil
+}
+
+// GetPaymentIntent returns what a customer needs to decide whether to pay
+func (s *MerchantService) GetPaymentIntent(code string) (*models.PaymentIntentResponse, error) {
+	var intent models.PaymentIntent
+	if err := s.db.Preload("Merchant").Where("code = ?", code).First(&intent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment intent not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return paymentIntentResponse(&intent), nil
+}
+
+// ConfirmPaymentIntent pays an open intent from the customer's points and
+// settles the amount to the merchant in one transaction. Payments of at least
+// the PIN threshold need the customer's transaction PIN.
+func (s *MerchantService) ConfirmPaymentIntent(customerID uint, code string, pin string) (*models.PaymentIntentResponse, error) {
+	var intent models.PaymentIntent
+	if err := s.db.Where("code = ?", code).First(&intent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment intent not found")
+		}
+		return nil, errors.New("database error")
+	}
+	// The amount of an intent never changes, so it can be checked before the transaction
+	if err := s.pins.Authorize(customerID, intent.Amount, pin); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Merchant").Where("code = ?", code).First(&intent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("payment intent not found")
+			}
+			return errors.New("database error")
+		}
+
+		if intent.Status != "requires_confirmation" {
+			return errors.New("payment intent is no longer open")
+		}
+		if intent.Merchant.Status != "active" {
+			return errors.New("merchant is not accepting payments")
+		}
+		now := time.Now()
+		if now.After(intent.ExpiresAt) {
+			return errors.New("payment intent expired")
+		}
+
+		if err := s.ledger.Debit(tx, customerID, intent.Amount); err != nil {
+			return err
+		}
+
+		entry := models.Transfer{
+			FromUserID: &customerID,
+			Amount:     intent.Amount,
+			Message:    paymentMessage(&intent),
+			Type:       "payment",
+			Status:     "completed",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		if err := tx.Model(&models.Merchant{}).Where("id = ?", intent.MerchantID).
+			Update("balance", gorm.Expr("balance + ?", intent.Amount)).Error; err != nil {
+			return errors.New("failed to settle payment")
+		}
+
+		// Only the first confirmation wins if two arrive at once
+		result := tx.Model(&models.PaymentIntent{}).
+			Where("id = ? AND status = ?", intent.ID, "requires_confirmation").
+			Updates(map[string]interface{}{
+				"status":       "succeeded",
+				"customer_id":  customerID,
+				"transfer_id":  entry.ID,
+				"confirmed_at": now,
+			})
+		if result.Error != nil {
+			return errors.New("failed to confirm payment intent")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("payment intent is no longer open")
+		}
+		intent.Status = "succeeded"
+
+		return nil
+	})
+	// Record the expiry outside the rolled back transaction
+	if err != nil && err.Error() == "payment intent expired" {
+		s.db.Model(&models.PaymentIntent{}).Where("id = ? AND status = ?", intent.ID, "requires_confirmation").
+			Update("status", "expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return paymentIntentResponse(&intent), nil
+}
+
+func (s *MerchantService) GetTransactions(merchantID uint) (*models.MerchantTransactionsResponse, error) {
+	var merchant models.Merchant
+	if err := s.db.First(&merchant, merchantID).Error; err != nil {
+		return nil, errors.New("merchant not found")
+	}
+
+	var intents []models.PaymentIntent
+	if err := s.db.Where("merchant_id = ? AND status = ?", merchantID, "succeeded").
+		Order("confirmed_at DESC").
+		Limit(100).
+		Find(&intents).Error; err != nil {
+		return nil, errors.New("failed to get transactions")
+	}
+
+	return &models.MerchantTransactionsResponse{
+		Balance:      merchant.Balance,
+		Transactions: intents,
+		Count:        len(intents),
+	}, nil
+}
+
+func paymentIntentResponse(intent *models.PaymentIntent) *models.PaymentIntentResponse {
+	return &models.PaymentIntentResponse{
+		ID:           intent.Code,
+		MerchantName: intent.Merchant.Name,
+		Amount:       intent.Amount,
+		Reference:    intent.Reference,
+		Description:  intent.Description,
+		Status:       intent.Status,
+		ExpiresAt:    intent.ExpiresAt,
+	}
+}
+
+func paymentMessage(intent *models.PaymentIntent) string {
+	message := "Payment to " + intent.Merchant.Name
+	if intent.Reference != "" {
+		message += " (" + intent.Reference + ")"
+	}
+	return message
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Number of recovery codes handed out when 2FA is enabled
+const recoveryCodeCount = 10
+
+// MFAService manages TOTP two-factor authentication and recovery codes
+type MFAService struct {
+	db          *gorm.DB
+	guard       *LoginGuard
+	issuer      string
+	maxAttempts int
+	lockout     time.Duration
+}
+
+func NewMFAService(db *gorm.DB, guard *LoginGuard, issuer string, maxAttempts int, lockout time.Duration) *MFAService {
+	return &MFAService{db: db, guard: guard, issuer: issuer, maxAttempts: maxAttempts, lockout: lockout}
+}
+
+// Enroll generates a new TOTP secret. 2FA stays off until Confirm receives a
+// code from the authenticator app, so enrolling again simply replaces the secret.
+func (s *MFAService) Enroll(userID uint, password, ip string) (*models.MFAEnrollmentResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, errors.New("mfa already enabled")
+	}
+	if err := s.guard.VerifyPassword(user, password, ip); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate secret")
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return nil, errors.New("failed to update user")
+	}
+
+	return &models.MFAEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm turns 2FA on once the user proves their app produces valid codes,
+// and returns the first set of recovery codes
+func (s *MFAService) Confirm(userID uint, code string) (*models.RecoveryCodesResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, errors.New("mfa already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("mfa not enrolled")
+	}
+	if err := s.checkCode(user, code); err != nil {
+		return nil, err
+	}
+
+	var response *models.RecoveryCodesResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("mfa_enabled", true).Error; err != nil {
+			return errors.New("failed to update user")
+		}
+
+		var err error
+		response, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RegenerateRecoveryCodes invalidates the remaining recovery codes and issues a new set
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, code string) (*models.RecoveryCodesResponse, error) {
+	user, err := s.enabledUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCode(user, code); err != nil {
+		return nil, err
+	}
+
+	var response *models.RecoveryCodesResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		response, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Disable turns 2FA off. It needs both the password and a current code.
+func (s *MFAService) Disable(userID uint, req models.DisableMFARequest, ip string) (*models.User, error) {
+	user, err := s.enabledUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guard.VerifyPassword(user, req.Password, ip); err != nil {
+		return nil, err
+	}
+	if err := s.checkCode(user, req.Code); err != nil {
+		return nil, err
+	}
+
+	return s.clear(userID)
+}
+
+// Reset turns 2FA off for a user who lost both their authenticator and recovery codes (admin)
+func (s *MFAService) Reset(userID uint) (*models.User, error) {
+	if _, err := s.user(userID); err != nil {
+		return nil, err
+	}
+	return s.clear(userID)
+}
+
+// StartLogin records a single-use nonce for a login waiting for its second
+// factor, to be put in the MFA token handed out in its place
+func (s *MFAService) StartLogin(userID uint, ttl time.Duration) (string, error) {
+	nonce, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND expires_at < ?", userID, "mfa_login", now).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return errors.New("database error")
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   "mfa_login",
+			TokenHash: utils.HashToken(nonce),
+			ExpiresAt: now.Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", errors.New("failed to create token")
+	}
+	return nonce, nil
+}
+
+// FinishLogin uses up the nonce of an MFA token, so each token completes at
+// most one login
+func (s *MFAService) FinishLogin(userID uint, nonce string) error {
+	now := time.Now()
+	result := s.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?",
+			userID, "mfa_login", utils.HashToken(nonce), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return errors.New("database error")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid or expired mfa token")
+	}
+	return nil
+}
+
+// Verify checks the second factor of a login: a TOTP code, or a recovery
+// code which is used up
+func (s *MFAService) Verify(userID uint, code, recoveryCode string) error {
+	user, err := s.enabledUser(userID)
+	if err != nil {
+		return err
+	}
+	if recoveryCode == "" {
+		return s.checkCode(user, code)
+	}
+
+	if s.locked(user) {
+		return errors.New("mfa locked")
+	}
+
+	hash := utils.HashToken(utils.NormalizeCode(recoveryCode))
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return errors.New("database error")
+	}
+	if result.RowsAffected == 0 {
+		return s.fail(user)
+	}
+
+	return s.succeed(user)
+}
+
+// checkCode validates a TOTP code, counting wrong codes towards the lockout
+func (s *MFAService) checkCode(user *models.User, code string) error {
+	if s.locked(user) {
+		return errors.New("mfa locked")
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return s.fail(user)
+	}
+
+	// Only the first request with a code may use it
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return errors.New("database error")
+	}
+	if result.RowsAffected == 0 {
+		return s.fail(user)
+	}
+
+	return s.succeed(user)
+}
+
+func (s *MFAService) locked(user *models.User) bool {
+	return user.MFALockedUntil != nil && time.Now().Before(*user.MFALockedUntil)
+}
+
+func (s *MFAService) succeed(user *models.User) error {
+	if user.MFAFailures == 0 && user.MFALockedUntil == nil {
+		return nil
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"mfa_failures":     0,
+		"mfa_locked_until": nil,
+	}).Error; err != nil {
+		return errors.New("database error")
+	}
+	return nil
+}
+
+// fail records a wrong code and locks 2FA after too many in a row
+func (s *MFAService) fail(user *models.User) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Update("mfa_failures", gorm.Expr("mfa_failures + 1")).Error; err != nil {
+			return err
+		}
+
+		var failures int
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Select("mfa_failures").Scan(&failures).Error; err != nil {
+			return err
+		}
+		if failures < s.maxAttempts {
+			return nil
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"mfa_failures":     0,
+			"mfa_locked_until": time.Now().Add(s.lockout),
+		}).Error
+	})
+	if err != nil {
+		return errors.New("database error")
+	}
+
+	return errors.New("invalid code")
+}
+
+func (s *MFAService) replaceRecoveryCodes(tx *gorm.DB, userID uint) (*models.RecoveryCodesResponse, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, errors.New("failed to replace recovery codes")
+	}
+
+	response := &models.RecoveryCodesResponse{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("failed to generate recovery codes")
+		}
+
+		record := models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(utils.NormalizeCode(code)),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return nil, errors.New("failed to create recovery codes")
+		}
+		response.RecoveryCodes = append(response.RecoveryCodes, code)
+	}
+
+	return response, nil
+}
+
+func (s *MFAService) clear(userID uint) (*models.User, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"mfa_enabled":      false,
+			"totp_secret":      "",
+			"totp_last_step":   0,
+			"mfa_failures":     0,
+			"mfa_locked_until": nil,
+		}).Error; err != nil {
+			return errors.New("failed to update user")
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return errors.New("failed to delete recovery codes")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.user(userID)
+}
+
+func (s *MFAService) enabledUser(userID uint) (*models.User, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, errors.New("mfa not enabled")
+	}
+	return user, nil
+}
+
+func (s *MFAService) user(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMFALoginNonce(t *testing.T) {
+	db := newTestDB(t)
+	mfa := NewMFAService(db, nil, "Test", 5, time.Minute)
+	user := newTestUser(t, db, "jane@example.com")
+	other := newTestUser(t, db, "john@example.com")
+
+	nonce, err := mfa.StartLogin(user.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	expired, err := mfa.StartLogin(user.ID, -time.Second)
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		nonce  string
+		ok     bool
+	}{
+		{"other user's login", other.ID, nonce, false},
+		{"unknown nonce", user.ID, "not-a-nonce", false},
+		{"expired nonce", user.ID, expired, false},
+		{"first use", user.ID, nonce, true},
+		{"replayed", user.ID, nonce, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := mfa.FinishLogin(tt.userID, tt.nonce); (err == nil) != tt.ok {
+				t.Errorf("err = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
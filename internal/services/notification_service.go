@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notifier delivers a short message to a user
+type Notifier interface {
+	Notify(tx *gorm.DB, userID uint, kind, message string) error
+}
+
+// NotificationService is the in-app Notifier; users read their messages with
+// GET /notifications
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Notify stores a notification. Pass a transaction to send it only if that transaction commits.
+func (s *NotificationService) Notify(tx *gorm.DB, userID uint, kind, message string) error {
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    kind,
+		Message: message,
+	}
+	if err := tx.Create(&notification).Error; err != nil {
+		return errors.New("failed to create notification")
+	}
+	return nil
+}
+
+func (s *NotificationService) List(userID uint) (*models.NotificationListResponse, error) {
+	var notifications []models.Notification
+	if err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&notifications).Error; err != nil {
+		return nil, errors.New("failed to get notifications")
+	}
+
+	response := &models.NotificationListResponse{
+		Notifications: notifications,
+		Count:         len(notifications),
+	}
+	for _, notification := range notifications {
+		if notification.ReadAt == nil {
+			response.Unread++
+		}
+	}
+
+	return response, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read
+func (s *NotificationService) MarkAllRead(userID uint) error {
+	if err := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error; err != nil {
+		return errors.New("failed to update notifications")
+	}
+	return nil
+}
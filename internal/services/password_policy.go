@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/breach"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Personal details shorter than this are too likely to occur by chance to
+// be rejected, e.g. a first name of "Al"
+const minPersonalInfoLength = 3
+
+// PasswordRules configures PasswordPolicy
+type PasswordRules struct {
+	MinLength          int  // Characters, not bytes
+	MaxLength          int  // Characters, 0 for no limit
+	RequireLower       bool // At least one lowercase letter
+	RequireUpper       bool // At least one uppercase letter
+	RequireDigit       bool
+	RequireSymbol      bool // At least one character that is not a letter or digit
+	RejectPersonalInfo bool // No email address, email name, first or last name inside the password
+}
+
+// PasswordPolicy decides which new passwords are acceptable. It applies
+// wherever a password is chosen: registration, change and reset. Existing
+// passwords keep working when the rules change.
+type PasswordPolicy struct {
+	rules    PasswordRules
+	breached breach.Checker
+}
+
+// NewPasswordPolicy enforces rules and, unless breached is nil, rejects
+// passwords found in its corpus
+func NewPasswordPolicy(rules PasswordRules, breached breach.Checker) *PasswordPolicy {
+	return &PasswordPolicy{rules: rules, breached: breached}
+}
+
+// Check returns why password is not acceptable for the user with this email
+// and name, or nil if it is
+func (p *PasswordPolicy) Check(password, email, firstName, lastName string) error {
+	r := p.rules
+
+	length := utf8.RuneCountInString(password)
+	if length < r.MinLength {
+		return fmt.Errorf("password must be at least %d characters", r.MinLength)
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", r.MaxLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		case !unicode.IsLetter(c):
+			symbol = true
+		}
+	}
+	switch {
+	case r.RequireLower && !lower:
+		return errors.New("password must contain a lowercase letter")
+	case r.RequireUpper && !upper:
+		return errors.New("password must contain an uppercase letter")
+	case r.RequireDigit && !digit:
+		return errors.New("password must contain a digit")
+	case r.RequireSymbol && !symbol:
+		return errors.New("password must contain a symbol")
+	}
+
+	if r.RejectPersonalInfo {
+		name, _, _ := strings.Cut(email, "@")
+		lowered := strings.ToLower(password)
+		for _, info := range []string{email, name, firstName, lastName} {
+			info = strings.ToLower(strings.TrimSpace(info))
+			if utf8.RuneCountInString(info) >= minPersonalInfoLength && strings.Contains(lowered, info) {
+				return errors.New("password must not contain your name or email")
+			}
+		}
+	}
+
+	if p.breached != nil {
+		found, err := p.breached.Breached(password)
+		if err != nil {
+			// An unreadable corpus must not stop everyone from signing up
+			log.Printf("Failed to check password against breached passwords: %v", err)
+		} else if found {
+			return errors.New("password has appeared in a data breach")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/mail"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fiber-api/internal/utils"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A user gets at most one reset email per this interval
+const resetRequestCooldown = time.Minute
+
+// PasswordResetService lets users who forgot their password set a new one
+// through a single-use link sent by email
+type PasswordResetService struct {
+	db        *gorm.DB
+	mailer    mail.Mailer
+	guard     *LoginGuard
+	passwords *PasswordPolicy
+	resetURL  string
+	ttl       time.Duration
+}
+
+func NewPasswordResetService(db *gorm.DB, mailer mail.Mailer, guard *LoginGuard, passwords *PasswordPolicy, resetURL string, ttl time.Duration) *PasswordResetService {
+	return &PasswordResetService{db: db, mailer: mailer, guard: guard, passwords: passwords, resetURL: resetURL, ttl: ttl}
+}
+
+// Forgot emails a reset link to the account with this email. It succeeds
+// whether or not there is such an account, so callers cannot probe for
+// registered addresses. Both cases cost the caller one lookup: the token and
+// the email for a known account are handled in the background, so response
+// times do not reveal the account either.
+func (s *PasswordResetService) Forgot(email, ip string) error {
+	email = normalizeEmail(email)
+
+	var user models.User
+	if err := s.db.Where("email_index = ?", pii.EmailIndex(email)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.New("database error")
+	}
+
+	go s.sendReset(user, email, ip)
+	return nil
+}
+
+// sendReset issues a new reset token for user and emails the link. Failures
+// are only logged, reporting them would reveal that the account exists.
+func (s *PasswordResetService) sendReset(user models.User, email, ip string) {
+	var recent int64
+	if err := s.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", user.ID, "password_reset", time.Now().Add(-resetRequestCooldown)).
+		Count(&recent).Error; err != nil {
+		log.Printf("Failed to check password reset cooldown of user %d: %v", user.ID, err)
+		return
+	}
+	if recent > 0 {
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		log.Printf("Failed to generate password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Only the newest link works
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, "password_reset").
+			Delete(&models.UserToken{}).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		record := models.UserToken{
+			UserID:    user.ID,
+			Purpose:   "password_reset",
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(s.ttl),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return errors.New("failed to create token")
+		}
+
+		return s.guard.Record(tx, "password_reset_requested", &user.ID, email, ip, "")
+	})
+	if err != nil {
+		log.Printf("Failed to create password reset token for user %d: %v", user.ID, err)
+		return
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"Someone asked to reset the password of your account. Open this link to choose a new one:\n\n"+
+		"%s?token=%s\n\n"+
+		"The link works once and expires in %d minutes. If you did not ask for it, you can ignore this email.\n",
+		user.FirstName, s.resetURL, token, int(s.ttl.Minutes()))
+	if err := s.mailer.Send(user.Email, "Reset your password", body); err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+}
+
+// Reset sets a new password with a token from Forgot. The token is used up,
+// every existing session and API key is revoked and any login lockout is
+// lifted.
+func (s *PasswordResetService) Reset(req models.ResetPasswordRequest, ip string) error {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var token models.UserToken
+		if err := tx.Where("token_hash = ? AND purpose = ?", utils.HashToken(req.Token), "password_reset").
+			First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid or expired token")
+			}
+			return errors.New("database error")
+		}
+		if !now.Before(token.ExpiresAt) {
+			return errors.New("invalid or expired token")
+		}
+
+		// Guard against the same link being used twice at once
+		result := tx.Model(&models.UserToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return errors.New("database error")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid or expired token")
+		}
+
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		// Rejecting the password rolls back the token, so the link still works
+		if err := s.passwords.Check(req.Password, user.Email, user.FirstName, user.LastName); err != nil {
+			return err
+		}
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			return errors.New("failed to hash password")
+		}
+
+		// Following the emailed link also proves the address is theirs
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"password":          hashedPassword,
+			"token_version":     gorm.Expr("token_version + 1"),
+			"email_verified":    true,
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", now),
+		}).Error; err != nil {
+			return errors.New("failed to update password")
+		}
+		if err := revokeSessions(tx, user.ID, 0, now); err != nil {
+			return err
+		}
+		if err := revokeAPIKeys(tx, s.guard, &user, ip, "password reset", now); err != nil {
+			return err
+		}
+
+		if err := recordProfileChanges(tx, models.ProfileChange{
+			UserID: user.ID,
+			Field:  "password",
+			Source: "password_reset",
+			IP:     ip,
+		}); err != nil {
+			return err
+		}
+
+		return s.guard.Record(tx, "password_reset", &user.ID, normalizeEmail(user.Email), ip, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.guard.Unlock(user.ID, user.Email, "password reset")
+}
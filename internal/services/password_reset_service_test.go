@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"fiber-api/internal/database"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fiber-api/internal/utils"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testPassword = "Correct-horse-battery-1"
+
+// newTestDB opens a migrated database in a temporary directory
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	provider, err := pii.NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, pii.KeySize)}, bytes.Repeat([]byte{9}, pii.KeySize))
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider: %v", err)
+	}
+	db := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"), pii.NewCipher(provider)).GetDB()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// newTestUser creates a user whose password is testPassword
+func newTestUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	t.Helper()
+	hashed, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	user := models.User{
+		Email:      email,
+		EmailIndex: pii.EmailIndex(email),
+		Password:   hashed,
+		FirstName:  "Jane",
+		LastName:   "Doe",
+		LBKCode:    "LBK-" + email,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return &user
+}
+
+func TestPasswordReplacementRevokesAPIKeys(t *testing.T) {
+	const newPassword = "Another-horse-battery-2"
+
+	tests := []struct {
+		name    string
+		replace func(t *testing.T, db *gorm.DB, guard *LoginGuard, policy *PasswordPolicy, user *models.User)
+	}{
+		{"password reset", func(t *testing.T, db *gorm.DB, guard *LoginGuard, policy *PasswordPolicy, user *models.User) {
+			token := models.UserToken{
+				UserID:    user.ID,
+				Purpose:   "password_reset",
+				TokenHash: utils.HashToken("reset-token"),
+				ExpiresAt: time.Now().Add(time.Hour),
+			}
+			if err := db.Create(&token).Error; err != nil {
+				t.Fatalf("create token: %v", err)
+			}
+			resets := NewPasswordResetService(db, nil, guard, policy, "", time.Hour)
+			if err := resets.Reset(models.ResetPasswordRequest{Token: "reset-token", Password: newPassword}, "192.0.2.1"); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+		}},
+		{"password change", func(t *testing.T, db *gorm.DB, guard *LoginGuard, policy *PasswordPolicy, user *models.User) {
+			profiles := NewProfileService(db, nil, guard, policy)
+			req := models.ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: newPassword}
+			if _, err := profiles.ChangePassword(user.ID, 0, req, "192.0.2.1"); err != nil {
+				t.Fatalf("ChangePassword: %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			guard := NewLoginGuard(db, LoginLimits{DelayAfter: 5, MaxAccountFailures: 10, MaxIPFailures: 50, Lockout: time.Minute})
+			policy := NewPasswordPolicy(PasswordRules{MinLength: 8}, nil)
+			keys := NewAPIKeyService(db, nil, guard)
+			user := newTestUser(t, db, "jane@example.com")
+
+			created, err := keys.Create(user.ID, models.CreateUserAPIKeyRequest{
+				Name:          "integration",
+				Scopes:        []string{ScopeTransfer},
+				TransferLimit: 1000,
+				Password:      testPassword,
+			}, "192.0.2.1")
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, _, err := keys.Authenticate(created.Key, "192.0.2.1"); err != nil {
+				t.Fatalf("Authenticate before: %v", err)
+			}
+
+			tt.replace(t, db, guard, policy, user)
+
+			if _, _, err := keys.Authenticate(created.Key, "192.0.2.1"); err == nil {
+				t.Error("a key created before the password was replaced still works")
+			}
+			var events int64
+			db.Model(&models.SecurityEvent{}).Where("user_id = ? AND type = ?", user.ID, "api_key_revoked").Count(&events)
+			if events != 1 {
+				t.Errorf("%d api_key_revoked events, want 1", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bills are split between at most this many participants
+const maxRequestParticipants = 20
+
+type PaymentRequestService struct {
+	db             *gorm.DB
+	notifier       Notifier
+	remindInterval time.Duration
+}
+
+func NewPaymentRequestService(db *gorm.DB, notifier Notifier, remindInterval time.Duration) *PaymentRequestService {
+	return &PaymentRequestService{db: db, notifier: notifier, remindInterval: remindInterval}
+}
+
+// Create splits a bill between the participants and notifies each of them
+// of their share
+func (s *PaymentRequestService) Create(requesterID uint, req models.CreatePaymentRequestRequest) (*models.PaymentRequest, error) {
+	currency := NormalizeCurrency(req.Currency)
+
+	var request models.PaymentRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := RequireActive(tx, currency); err != nil {
+			return err
+		}
+
+		var requester models.User
+		if err := tx.First(&requester, requesterID).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		request = models.PaymentRequest{
+			RequesterID: requesterID,
+			Title:       req.Title,
+			Currency:    currency,
+			Status:      "open",
+		}
+
+		var codes []string
+		var amounts []uint
+		switch req.Split {
+		case "", "even":
+			request.SplitMode = "even"
+			request.TotalAmount = req.TotalAmount
+			codes = req.Participants
+
+			parts := uint(len(codes))
+			if req.IncludeRequester {
+				parts++
+			}
+			if parts == 0 || req.TotalAmount < parts {
+				return errors.New("total_amount is too small to split")
+			}
+			// Spread the remainder one point at a time over the first participants
+			base, extra := req.TotalAmount/parts, req.TotalAmount%parts
+			for i := range codes {
+				amount := base
+				if uint(i) < extra {
+					amount++
+				}
+				amounts = append(amounts, amount)
+			}
+			if req.IncludeRequester {
+				request.RequesterShare = base
+			}
+		case "custom":
+			request.SplitMode = "custom"
+			for _, share := range req.Shares {
+				if share.Amount == 0 {
+					return errors.New("every share needs an amount")
+				}
+				codes = append(codes, share.LBKCode)
+				amounts = append(amounts, share.Amount)
+				request.TotalAmount += share.Amount
+			}
+		default:
+			return errors.New("split must be even or custom")
+		}
+
+		if len(codes) == 0 {
+			return errors.New("at least one participant is required")
+		}
+		if len(codes) > maxRequestParticipants {
+			return fmt.Errorf("at most %d participants are allowed", maxRequestParticipants)
+		}
+
+		seen := map[string]bool{}
+		for i, code := range codes {
+			code = strings.ToUpper(strings.TrimSpace(code))
+			if seen[code] {
+				return errors.New("participants must be unique")
+			}
+			seen[code] = true
+
+			var participant models.User
+			if err := tx.Where("lbk_code = ? AND closed_at IS NULL", code).First(&participant).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("participant not found: " + code)
+				}
+				return errors.New("database error")
+			}
+			if participant.ID == requesterID {
+				return errors.New("cannot request payment from yourself")
+			}
+
+			request.Shares = append(request.Shares, models.PaymentRequestShare{
+				UserID:  participant.ID,
+				LBKCode: participant.LBKCode,
+				Amount:  amounts[i],
+				Status:  "pending",
+			})
+		}
+
+		if err := tx.Create(&request).Error; err != nil {
+			return errors.New("failed to create payment request")
+		}
+
+		name := shortName(requester.FirstName, requester.LastName)
+		for _, share := range request.Shares {
+			message := fmt.Sprintf("%s asks you to pay %d %s for %s", name, share.Amount, currency, request.Title)
+			if err := s.notifier.Notify(tx, share.UserID, "payment_request", message); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// List returns the requests the user created or has a share in
+func (s *PaymentRequestService) List(userID uint) (*models.PaymentRequestListResponse, error) {
+	var requests []models.PaymentRequest
+	if err := s.db.Preload("Shares").
+		Where("requester_id = ? OR id IN (SELECT request_id FROM payment_request_shares WHERE user_id = ?)", userID, userID).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&requests).Error; err != nil {
+		return nil, errors.New("failed to get payment requests")
+	}
+
+	return &models.PaymentRequestListResponse{
+		Requests: requests,
+		Count:    len(requests),
+	}, nil
+}
+
+// Get returns a request visible to the user as requester or participant
+func (s *PaymentRequestService) Get(userID, requestID uint) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	if err := s.db.Preload("Shares").
+		Where("id = ? AND (requester_id = ? OR id IN (SELECT request_id FROM payment_request_shares WHERE user_id = ?))", requestID, userID, userID).
+		First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment request not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &request, nil
+}
+
+// ShareTransfer builds the transfer that pays the user's share of a request
+func (s *PaymentRequestService) ShareTransfer(userID, requestID uint) (*models.TransferRequest, error) {
+	request, err := s.Get(userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != "open" {
+		return nil, errors.New("payment request is closed")
+	}
+
+	for _, share := range request.Shares {
+		if share.UserID != userID {
+			continue
+		}
+		if share.Status == "paid" {
+			return nil, errors.New("share already paid")
+		}
+
+		var requester models.User
+		if err := s.db.Select("lbk_code").First(&requester, request.RequesterID).Error; err != nil {
+			return nil, errors.New("database error")
+		}
+
+		return &models.TransferRequest{
+			ToLBKCode:        requester.LBKCode,
+			Amount:           share.Amount,
+			Currency:         request.Currency,
+			Message:          request.Title,
+			PaymentRequestID: &request.ID,
+		}, nil
+	}
+
+	return nil, errors.New("you have no share in this payment request")
+}
+
+// Settle marks the payer's share as paid by a transfer and closes the
+// request once nobody owes anything. It is called by TransferPoints and must
+// run inside its transaction.
+func (s *PaymentRequestService) Settle(tx *gorm.DB, requestID, payerID, payeeID uint, amount uint, currency string, transferID uint) error {
+	var request models.PaymentRequest
+	if err := tx.First(&request, requestID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("payment request not found")
+		}
+		return errors.New("database error")
+	}
+	if request.Status != "open" {
+		return errors.New("payment request is closed")
+	}
+	if request.RequesterID != payeeID {
+		return errors.New("transfer must go to the requester")
+	}
+	if request.Currency != currency {
+		return errors.New("currency does not match payment request")
+	}
+
+	var share models.PaymentRequestShare
+	if err := tx.Where("request_id = ? AND user_id = ?", requestID, payerID).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("you have no share in this payment request")
+		}
+		return errors.New("database error")
+	}
+	if share.Amount != amount {
+		return errors.New("amount does not match your share")
+	}
+
+	now := time.Now()
+	result := tx.Model(&models.PaymentRequestShare{}).
+		Where("id = ? AND status = ?", share.ID, "pending").
+		Updates(map[string]interface{}{
+			"status":      "paid",
+			"transfer_id": transferID,
+			"paid_at":     now,
+		})
+	if result.Error != nil {
+		return errors.New("failed to update share")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("share already paid")
+	}
+
+	var pending int64
+	if err := tx.Model(&models.PaymentRequestShare{}).
+		Where("request_id = ? AND status = ?", requestID, "pending").
+		Count(&pending).Error; err != nil {
+		return errors.New("database error")
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	if err := tx.Model(&request).Updates(map[string]interface{}{
+		"status":    "paid",
+		"closed_at": now,
+	}).Error; err != nil {
+		return errors.New("failed to close payment request")
+	}
+
+	return s.notifier.Notify(tx, request.RequesterID, "payment_request", "Everyone has paid for "+request.Title)
+}
+
+// Cancel closes an open request. Shares already paid are not refunded.
+func (s *PaymentRequestService) Cancel(requesterID, requestID uint) (*models.PaymentRequest, error) {
+	result := s.db.Model(&models.PaymentRequest{}).
+		Where("id = ? AND requester_id = ? AND status = ?", requestID, requesterID, "open").
+		Updates(map[string]interface{}{
+			"status":    "cancelled",
+			"closed_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, errors.New("failed to cancel payment request")
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(requesterID, requestID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("payment request is closed")
+	}
+
+	return s.Get(requesterID, requestID)
+}
+
+// Remind nudges every participant of the requester's request who has not
+// paid and was not reminded within the reminder interval
+func (s *PaymentRequestService) Remind(requesterID, requestID uint) (int, error) {
+	var request models.PaymentRequest
+	if err := s.db.Where("id = ? AND requester_id = ?", requestID, requesterID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("payment request not found")
+		}
+		return 0, errors.New("database error")
+	}
+	if request.Status != "open" {
+		return 0, errors.New("payment request is closed")
+	}
+
+	return s.remind(s.db.Where("request_id = ?", request.ID), time.Now())
+}
+
+// RemindOverdue reminds everyone who still owes on an open request and has
+// not heard about it within the reminder interval
+func (s *PaymentRequestService) RemindOverdue(now time.Time) (int, error) {
+	return s.remind(s.db.Where("request_id IN (SELECT id FROM payment_requests WHERE status = ?)", "open").
+		Where("created_at <= ?", now.Add(-s.remindInterval)), now)
+}
+
+func (s *PaymentRequestService) remind(query *gorm.DB, now time.Time) (int, error) {
+	var shares []models.PaymentRequestShare
+	if err := query.Where("status = ?", "pending").
+		Where("reminded_at IS NULL OR reminded_at <= ?", now.Add(-s.remindInterval)).
+		Find(&shares).Error; err != nil {
+		return 0, errors.New("failed to load shares")
+	}
+
+	reminded := 0
+	for _, share := range shares {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var request models.PaymentRequest
+			if err := tx.First(&request, share.RequestID).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&share).Updates(map[string]interface{}{
+				"reminded_at":    now,
+				"reminder_count": gorm.Expr("reminder_count + 1"),
+			}).Error; err != nil {
+				return err
+			}
+
+			message := fmt.Sprintf("Reminder: you still owe %d %s for %s", share.Amount, request.Currency, request.Title)
+			return s.notifier.Notify(tx, share.UserID, "payment_reminder", message)
+		})
+		if err != nil {
+			log.Printf("Failed to remind share %d: %v", share.ID, err)
+			continue
+		}
+		reminded++
+	}
+
+	return reminded, nil
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fiber-api/internal/sms"
+	"fiber-api/internal/utils"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Digits in a phone verification code
+const otpLength = 6
+
+// PhoneOTPLimits configures PhoneService
+type PhoneOTPLimits struct {
+	TTL            time.Duration // How long a code works
+	MaxAttempts    int           // Wrong entries before a code is used up
+	ResendCooldown time.Duration // Minimum time between two codes to one user
+	MaxPerDay      int           // Codes to one user in 24 hours, 0 for no limit
+}
+
+// PhoneService normalizes phone numbers to E.164 and verifies them with
+// one-time codes sent by SMS
+type PhoneService struct {
+	db          *gorm.DB
+	sender      sms.SMSSender
+	countryCode string
+	limits      PhoneOTPLimits
+}
+
+func NewPhoneService(db *gorm.DB, sender sms.SMSSender, countryCode string, limits PhoneOTPLimits) *PhoneService {
+	return &PhoneService{db: db, sender: sender, countryCode: countryCode, limits: limits}
+}
+
+// Normalize converts a number to E.164. Numbers in national format are taken
+// to be in the default country.
+func (s *PhoneService) Normalize(number string) (string, error) {
+	return utils.NormalizePhone(number, s.countryCode)
+}
+
+// NormalizeExisting converts the numbers of users created before numbers were
+// normalized. Numbers that cannot be read are left alone and logged.
+func (s *PhoneService) NormalizeExisting() error {
+	// Numbers are encrypted, so they are checked here rather than in SQL
+	var users []models.User
+	if err := s.db.Select("id, phone_number").
+		Where("phone_number <> ''").
+		Find(&users).Error; err != nil {
+		return errors.New("failed to load users")
+	}
+
+	for _, user := range users {
+		if strings.HasPrefix(user.PhoneNumber, "+") {
+			continue
+		}
+		number, err := s.Normalize(user.PhoneNumber)
+		if err != nil {
+			log.Printf("Cannot normalize phone number of user %d: %v", user.ID, err)
+			continue
+		}
+		if err := s.db.Model(&user).Updates(map[string]interface{}{
+			"phone_number": pii.Seal("users", "phone_number", number),
+			"phone_index":  pii.PhoneIndex(number),
+		}).Error; err != nil {
+			return errors.New("failed to update phone number")
+		}
+	}
+
+	return nil
+}
+
+// ChangeNumber sets a new, unverified number for the user and texts it a code
+func (s *PhoneService) ChangeNumber(userID uint, number, ip string) (*models.User, error) {
+	number, err := s.Normalize(number)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PhoneNumber == number && user.PhoneVerified {
+		return nil, errors.New("phone number already verified")
+	}
+	if err := s.checkAvailable(s.db, userID, number); err != nil {
+		return nil, err
+	}
+	if err := s.checkLimits(userID); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"phone_number":      pii.Seal("users", "phone_number", number),
+			"phone_index":       pii.PhoneIndex(number),
+			"phone_verified":    false,
+			"phone_verified_at": nil,
+		}).Error; err != nil {
+			return errors.New("failed to update phone number")
+		}
+		if number == user.PhoneNumber {
+			// Same number, not yet verified; only a new code is sent
+			return nil
+		}
+		return recordProfileChanges(tx, models.ProfileChange{
+			UserID:   userID,
+			Field:    "phone_number",
+			OldValue: user.PhoneNumber,
+			NewValue: number,
+			Source:   "phone",
+			IP:       ip,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.PhoneNumber = number
+	user.PhoneVerified = false
+	user.PhoneVerifiedAt = nil
+
+	if err := s.send(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SendCode texts a new code to the user's current number
+func (s *PhoneService) SendCode(userID uint) error {
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+	if user.PhoneNumber == "" {
+		return errors.New("no phone number")
+	}
+	if user.PhoneVerified {
+		return errors.New("phone number already verified")
+	}
+	if !strings.HasPrefix(user.PhoneNumber, "+") {
+		return errors.New("invalid phone number")
+	}
+	if err := s.checkLimits(userID); err != nil {
+		return err
+	}
+
+	return s.send(user)
+}
+
+// Verify checks the latest code sent to the user's number and marks the number verified
+func (s *PhoneService) Verify(userID uint, code string) (*models.User, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PhoneVerified {
+		return nil, errors.New("phone number already verified")
+	}
+
+	var verification models.PhoneVerification
+	if err := s.db.Where("user_id = ? AND verified_at IS NULL", userID).
+		Order("created_at DESC").
+		First(&verification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no code sent")
+		}
+		return nil, errors.New("database error")
+	}
+	// Numbers are encrypted, so the latest code is matched to the number here
+	if verification.PhoneNumber != user.PhoneNumber {
+		return nil, errors.New("no code sent")
+	}
+
+	now := time.Now()
+	if !now.Before(verification.ExpiresAt) {
+		return nil, errors.New("code expired")
+	}
+	if verification.Attempts >= s.limits.MaxAttempts {
+		return nil, errors.New("too many attempts")
+	}
+
+	if !utils.CheckPassword(code, verification.CodeHash) {
+		if err := s.db.Model(&verification).Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+			return nil, errors.New("database error")
+		}
+		return nil, errors.New("invalid code")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Guard against the same code being used twice at once
+		result := tx.Model(&models.PhoneVerification{}).
+			Where("id = ? AND verified_at IS NULL", verification.ID).
+			Update("verified_at", now)
+		if result.Error != nil {
+			return errors.New("database error")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid code")
+		}
+
+		if err := s.checkAvailable(tx, userID, user.PhoneNumber); err != nil {
+			return err
+		}
+
+		// The number must not have changed since the code was sent
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND phone_index = ?", userID, pii.PhoneIndex(verification.PhoneNumber)).
+			Updates(map[string]interface{}{
+				"phone_verified":    true,
+				"phone_verified_at": now,
+			})
+		if result.Error != nil {
+			// The unique index catches a concurrent verification by another user
+			return errors.New("phone number already in use")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid code")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.PhoneVerified = true
+	user.PhoneVerifiedAt = &now
+	return user, nil
+}
+
+// checkAvailable fails when another user has verified number
+func (s *PhoneService) checkAvailable(tx *gorm.DB, userID uint, number string) error {
+	var taken int64
+	if err := tx.Model(&models.User{}).
+		Where("phone_index = ? AND phone_verified = ? AND id <> ?", pii.PhoneIndex(number), true, userID).
+		Count(&taken).Error; err != nil {
+		return errors.New("database error")
+	}
+	if taken > 0 {
+		return errors.New("phone number already in use")
+	}
+	return nil
+}
+
+// checkLimits enforces the resend cooldown and the daily cap on codes, which cost money to send
+func (s *PhoneService) checkLimits(userID uint) error {
+	now := time.Now()
+	var sent []models.PhoneVerification
+	if err := s.db.Where("user_id = ? AND created_at > ?", userID, now.Add(-24*time.Hour)).
+		Order("created_at DESC").
+		Find(&sent).Error; err != nil {
+		return errors.New("database error")
+	}
+	if len(sent) > 0 && now.Sub(sent[0].CreatedAt) < s.limits.ResendCooldown {
+		return errors.New("code recently sent")
+	}
+	if s.limits.MaxPerDay > 0 && len(sent) >= s.limits.MaxPerDay {
+		return errors.New("too many codes requested")
+	}
+	return nil
+}
+
+func (s *PhoneService) send(user *models.User) error {
+	code, err := utils.GenerateOTP(otpLength)
+	if err != nil {
+		return errors.New("failed to generate code")
+	}
+	hash, err := utils.HashPassword(code)
+	if err != nil {
+		return errors.New("failed to hash code")
+	}
+
+	verification := models.PhoneVerification{
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		CodeHash:    hash,
+		ExpiresAt:   time.Now().Add(s.limits.TTL),
+	}
+	if err := s.db.Create(&verification).Error; err != nil {
+		return errors.New("failed to create code")
+	}
+
+	message := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(s.limits.TTL.Minutes()))
+	if err := s.sender.Send(user.PhoneNumber, message); err != nil {
+		return errors.New("failed to send code")
+	}
+	return nil
+}
+
+func (s *PhoneService) user(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
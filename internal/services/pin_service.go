@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"log"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var pinPattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+// PINService manages transaction PINs, the step-up check for high-value transfers
+type PINService struct {
+	db          *gorm.DB
+	guard       *LoginGuard
+	threshold   uint
+	maxAttempts int
+	lockout     time.Duration
+}
+
+func NewPINService(db *gorm.DB, guard *LoginGuard, threshold uint, maxAttempts int, lockout time.Duration) *PINService {
+	return &PINService{db: db, guard: guard, threshold: threshold, maxAttempts: maxAttempts, lockout: lockout}
+}
+
+func (s *PINService) Status(userID uint) (*models.PINStatusResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.PINStatusResponse{
+		Set:       user.PINHash != "",
+		Threshold: s.threshold,
+	}
+	if user.PINLockedUntil != nil && time.Now().Before(*user.PINLockedUntil) {
+		response.LockedUntil = user.PINLockedUntil
+	}
+	return response, nil
+}
+
+// Set creates the user's first PIN. The account password confirms it is the owner.
+func (s *PINService) Set(userID uint, req models.SetPINRequest, ip string) error {
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+	if user.PINHash != "" {
+		return errors.New("pin already set")
+	}
+	if err := s.guard.VerifyPassword(user, req.Password, ip); err != nil {
+		return err
+	}
+
+	return s.store(userID, req.PIN)
+}
+
+// Change replaces the PIN after checking the current one. Wrong PINs count
+// towards the lockout like they do on transfers.
+func (s *PINService) Change(userID uint, req models.ChangePINRequest) error {
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+	if user.PINHash == "" {
+		return errors.New("pin not set")
+	}
+	if err := s.verify(user, req.CurrentPIN); err != nil {
+		return err
+	}
+
+	return s.store(userID, req.NewPIN)
+}
+
+// Reset replaces a forgotten PIN using the account password and lifts any lockout
+func (s *PINService) Reset(userID uint, req models.ResetPINRequest, ip string) error {
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+	if user.PINHash == "" {
+		return errors.New("pin not set")
+	}
+	if err := s.guard.VerifyPassword(user, req.Password, ip); err != nil {
+		return err
+	}
+
+	return s.store(userID, req.NewPIN)
+}
+
+// Authorize checks the PIN for a transfer of amount points. Transfers below
+// the threshold pass without one. Call it outside the transfer's transaction
+// so failed attempts are kept when the transfer is rolled back.
+func (s *PINService) Authorize(userID uint, amount uint, pin string) error {
+	if s.threshold == 0 || amount < s.threshold {
+		return nil
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return err
+	}
+	if user.PINHash == "" {
+		return errors.New("pin not set")
+	}
+	if pin == "" {
+		return errors.New("pin required")
+	}
+
+	return s.verify(user, pin)
+}
+
+// verify checks the PIN, locking the user out after too many wrong attempts
+// in a row. Each attempt is claimed before the hash is compared, so parallel
+// guesses can neither slip past the lock nor exceed maxAttempts per lockout.
+func (s *PINService) verify(user *models.User, pin string) error {
+	now := time.Now()
+
+	var current models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// An expired lock starts a new run of attempts
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND pin_locked_until IS NOT NULL AND pin_locked_until <= ?", user.ID, now).
+			Updates(map[string]interface{}{
+				"pin_failures":     0,
+				"pin_locked_until": nil,
+			}).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		// Count the attempt as failed up front; a correct PIN clears it
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND pin_locked_until IS NULL AND pin_failures < ?", user.ID, s.maxAttempts).
+			Update("pin_failures", gorm.Expr("pin_failures + 1"))
+		if result.Error != nil {
+			return errors.New("database error")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("pin locked")
+		}
+
+		if err := tx.First(&current, user.ID).Error; err != nil {
+			return errors.New("database error")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if utils.CheckPassword(pin, current.PINHash) {
+		if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"pin_failures":     0,
+			"pin_locked_until": nil,
+		}).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		// PINs are hashed like passwords and upgraded the same way
+		if utils.PasswordNeedsRehash(current.PINHash) {
+			s.rehash(&current, pin)
+		}
+		return nil
+	}
+
+	// The count stays at the maximum until the lock expires, so no further
+	// attempt is claimed meanwhile
+	if current.PINFailures >= s.maxAttempts {
+		if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).
+			Update("pin_locked_until", now.Add(s.lockout)).Error; err != nil {
+			return errors.New("database error")
+		}
+	}
+
+	return errors.New("invalid pin")
+}
+
+func (s *PINService) store(userID uint, pin string) error {
+	if !pinPattern.MatchString(pin) {
+		return errors.New("pin must be 6 digits")
+	}
+
+	hash, err := utils.HashPassword(pin)
+	if err != nil {
+		return errors.New("failed to hash pin")
+	}
+
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"pin_hash":         hash,
+		"pin_failures":     0,
+		"pin_locked_until": nil,
+	}).Error; err != nil {
+		return errors.New("failed to update pin")
+	}
+	return nil
+}
+
+// rehash stores a new hash of pin unless the PIN changed meanwhile. A failure
+// is only logged; the next correct PIN tries again.
+func (s *PINService) rehash(user *models.User, pin string) {
+	hash, err := utils.HashPassword(pin)
+	if err != nil {
+		log.Printf("Failed to rehash PIN of user %d: %v", user.ID, err)
+		return
+	}
+	if err := s.db.Model(&models.User{}).
+		Where("id = ? AND pin_hash = ?", user.ID, user.PINHash).
+		Update("pin_hash", hash).Error; err != nil {
+		log.Printf("Failed to rehash PIN of user %d: %v", user.ID, err)
+	}
+}
+
+func (s *PINService) user(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
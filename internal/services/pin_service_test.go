@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fiber-api/internal/models"
+	"testing"
+	"time"
+)
+
+func TestPINLockout(t *testing.T) {
+	const maxAttempts = 3
+
+	db := newTestDB(t)
+	pins := NewPINService(db, nil, 1000, maxAttempts, time.Minute)
+	user := newTestUser(t, db, "jane@example.com")
+	if err := pins.store(user.ID, "123456"); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	// Every guess gets the same stale row, like parallel requests loaded
+	// before any of them failed
+	stale, err := pins.user(user.ID)
+	if err != nil {
+		t.Fatalf("user: %v", err)
+	}
+
+	var invalid, locked int
+	for i := 0; i < 10; i++ {
+		switch err := pins.verify(stale, "000000"); {
+		case err == nil:
+			t.Fatal("a wrong PIN was accepted")
+		case err.Error() == "invalid pin":
+			invalid++
+		case err.Error() == "pin locked":
+			locked++
+		default:
+			t.Fatalf("verify: %v", err)
+		}
+	}
+	if invalid != maxAttempts || locked != 10-maxAttempts {
+		t.Errorf("%d invalid and %d locked, want %d and %d", invalid, locked, maxAttempts, 10-maxAttempts)
+	}
+
+	if err := pins.verify(stale, "123456"); err == nil || err.Error() != "pin locked" {
+		t.Errorf("correct PIN while locked: err = %v, want pin locked", err)
+	}
+
+	// Once the lock expires a new run of attempts starts
+	if err := db.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("pin_locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := pins.verify(stale, "000000"); err == nil || err.Error() != "invalid pin" {
+		t.Errorf("wrong PIN after the lock expired: err = %v, want invalid pin", err)
+	}
+	if err := pins.verify(stale, "123456"); err != nil {
+		t.Errorf("correct PIN after the lock expired: %v", err)
+	}
+
+	fresh, err := pins.user(user.ID)
+	if err != nil {
+		t.Fatalf("user: %v", err)
+	}
+	if fresh.PINFailures != 0 || fresh.PINLockedUntil != nil {
+		t.Errorf("after a correct PIN: failures = %d, locked until %v", fresh.PINFailures, fresh.PINLockedUntil)
+	}
+}
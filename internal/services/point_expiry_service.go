@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PointExpiryService struct {
+	db     *gorm.DB
+	ledger *PointLedger
+}
+
+func NewPointExpiryService(db *gorm.DB, ledger *PointLedger) *PointExpiryService {
+	return &PointExpiryService{db: db, ledger: ledger}
+}
+
+// BackfillLots creates a lot for any wallet balance that is not yet backed
+// by lots, such as points held before lot tracking existed.
+func (s *PointExpiryService) BackfillLots() error {
+	var wallets []models.Wallet
+	if err := s.db.Where("balance > 0").Find(&wallets).Error; err != nil {
+		return errors.New("failed to load wallets")
+	}
+
+	for _, wallet := range wallets {
+		var lotted uint
+		if err := s.db.Model(&models.PointLot{}).
+			Where("user_id = ? AND currency = ?", wallet.UserID, wallet.Currency).
+			Select("COALESCE(SUM(remaining), 0)").
+			Scan(&lotted).Error; err != nil {
+			return errors.New("failed to sum point lots")
+		}
+		if lotted >= wallet.Balance {
+			continue
+		}
+
+		lot := s.ledger.newLot(wallet.UserID, wallet.Currency, wallet.Balance-lotted, "migration")
+		if err := s.db.Create(&lot).Error; err != nil {
+			return errors.New("failed to create point lot")
+		}
+	}
+
+	return nil
+}
+
+// ExpireLots forfeits the remaining points of every lot that expired at or
+// before now and records an expiry entry in the owner's history.
+func (s *PointExpiryService) ExpireLots(now time.Time) (int, error) {
+	var lots []models.PointLot
+	if err := s.db.Where("expires_at <= ? AND remaining > 0", now).Find(&lots).Error; err != nil {
+		return 0, errors.New("failed to load expired lots")
+	}
+
+	expired := 0
+	for _, lot := range lots {
+		if err := s.expireLot(lot, now); err != nil {
+			log.Printf("Failed to expire point lot %d: %v", lot.ID, err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+func (s *PointExpiryService) expireLot(lot models.PointLot, now time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Re-check inside the transaction in case a transfer spent the lot meanwhile
+		result := tx.Model(&models.PointLot{}).
+			Where("id = ? AND remaining = ?", lot.ID, lot.Remaining).
+			Updates(map[string]interface{}{"remaining": 0, "expired_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("lot changed while expiring")
+		}
+
+		ok, err := s.ledger.withdraw(tx, lot.UserID, lot.Currency, lot.Remaining)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("wallet out of sync with point lots")
+		}
+
+		entry := models.Transfer{
+			FromUserID: &lot.UserID,
+			Amount:     lot.Remaining,
+			Currency:   lot.Currency,
+			Message:    "Points expired",
+			Type:       "expiry",
+			Status:     "expired",
+		}
+		return tx.Create(&entry).Error
+	})
+}
+
+func (s *PointExpiryService) GetExpiringSoon(userID uint, currency string, days int) (*models.ExpiringPointsResponse, error) {
+	until := time.Now().AddDate(0, 0, days)
+
+	var lots []models.PointLot
+	if err := s.db.Where("user_id = ? AND currency = ? AND remaining > 0 AND expires_at IS NOT NULL AND expires_at <= ?", userID, currency, until).
+		Order("expires_at, id").
+		Find(&lots).Error; err != nil {
+		return nil, errors.New("failed to get expiring points")
+	}
+
+	response := &models.ExpiringPointsResponse{
+		Currency: currency,
+		Days:     days,
+		Lots:     []models.ExpiringLot{},
+	}
+	for _, lot := range lots {
+		response.TotalExpiring += lot.Remaining
+		response.Lots = append(response.Lots, models.ExpiringLot{
+			LotID:     lot.ID,
+			Currency:  lot.Currency,
+			Remaining: lot.Remaining,
+			Source:    lot.Source,
+			GrantedAt: lot.GrantedAt,
+			ExpiresAt: *lot.ExpiresAt,
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PointLedger moves points in and out of user wallets. Every credit creates a
+// lot carrying its own expiry date and every debit consumes lots FIFO, so a
+// wallet's balance always equals the sum of its remaining lots. The default
+// currency's balance is mirrored into users.point_balance.
+type PointLedger struct {
+	expiryDays int
+}
+
+func NewPointLedger(expiryDays int) *PointLedger {
+	return &PointLedger{expiryDays: expiryDays}
+}
+
+// Credit grants amount points of the default currency. It must run inside tx.
+func (l *PointLedger) Credit(tx *gorm.DB, userID uint, amount uint, source string) error {
+	return l.CreditCurrency(tx, userID, models.DefaultCurrency, amount, source)
+}
+
+// Debit removes amount points of the default currency. It must run inside tx.
+func (l *PointLedger) Debit(tx *gorm.DB, userID uint, amount uint) error {
+	return l.DebitCurrency(tx, userID, models.DefaultCurrency, amount)
+}
+
+// CreditCurrency grants amount to the user's wallet in currency as a new lot,
+// opening the wallet if needed. It must run inside tx.
+func (l *PointLedger) CreditCurrency(tx *gorm.DB, userID uint, currency string, amount uint, source string) error {
+	lot := l.newLot(userID, currency, amount, source)
+	if err := tx.Create(&lot).Error; err != nil {
+		return errors.New("failed to create point lot")
+	}
+
+	wallet := models.Wallet{UserID: userID, Currency: currency}
+	if err := tx.Where(&wallet).FirstOrCreate(&wallet).Error; err != nil {
+		return errors.New("failed to open wallet")
+	}
+	if err := tx.Model(&wallet).Update("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
+		return errors.New("failed to update balance")
+	}
+
+	if currency == models.DefaultCurrency {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("point_balance", gorm.Expr("point_balance + ?", amount)).Error; err != nil {
+			return errors.New("failed to update balance")
+		}
+	}
+
+	return nil
+}
+
+// DebitCurrency removes amount from the user's wallet in currency, spending
+// the lots that expire soonest first. It must run inside tx.
+func (l *PointLedger) DebitCurrency(tx *gorm.DB, userID uint, currency string, amount uint) error {
+	ok, err := l.withdraw(tx, userID, currency, amount)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("insufficient points")
+	}
+
+	var lots []models.PointLot
+	if err := tx.Where("user_id = ? AND currency = ? AND remaining > 0", userID, currency).
+		Order("expires_at IS NULL, expires_at, granted_at, id").
+		Find(&lots).Error; err != nil {
+		return errors.New("failed to load point lots")
+	}
+
+	remaining := amount
+	for _, lot := range lots {
+		if remaining == 0 {
+			break
+		}
+		take := lot.Remaining
+		if take > remaining {
+			take = remaining
+		}
+		if err := tx.Model(&lot).Update("remaining", lot.Remaining-take).Error; err != nil {
+			return errors.New("failed to update point lot")
+		}
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		return errors.New("point lots out of sync with balance")
+	}
+
+	return nil
+}
+
+// withdraw lowers the wallet balance only if it covers amount, reporting
+// whether it did. Lots are left to the caller.
+func (l *PointLedger) withdraw(tx *gorm.DB, userID uint, currency string, amount uint) (bool, error) {
+	result := tx.Model(&models.Wallet{}).
+		Where("user_id = ? AND currency = ? AND balance >= ?", userID, currency, amount).
+		Update("balance", gorm.Expr("balance - ?", amount))
+	if result.Error != nil {
+		return false, errors.New("failed to update balance")
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if currency == models.DefaultCurrency {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("point_balance", gorm.Expr("point_balance - ?", amount)).Error; err != nil {
+			return false, errors.New("failed to update balance")
+		}
+	}
+
+	return true, nil
+}
+
+// newLot builds an unsaved lot granted now with the configured lifetime
+func (l *PointLedger) newLot(userID uint, currency string, amount uint, source string) models.PointLot {
+	now := time.Now()
+	lot := models.PointLot{
+		UserID:    userID,
+		Currency:  currency,
+		Amount:    amount,
+		Remaining: amount,
+		Source:    source,
+		GrantedAt: now,
+	}
+	if l.expiryDays > 0 {
+		expiresAt := now.AddDate(0, 0, l.expiryDays)
+		lot.ExpiresAt = &expiresAt
+	}
+	return lot
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fiber-api/internal/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Longest first or last name accepted
+const maxNameLength = 100
+
+// ProfileService lets users change their profile and password after
+// registration, keeping a history of every change
+type ProfileService struct {
+	db        *gorm.DB
+	phones    *PhoneService
+	guard     *LoginGuard
+	passwords *PasswordPolicy
+}
+
+func NewProfileService(db *gorm.DB, phones *PhoneService, guard *LoginGuard, passwords *PasswordPolicy) *ProfileService {
+	return &ProfileService{db: db, phones: phones, guard: guard, passwords: passwords}
+}
+
+// Update changes the fields present in req. A new phone number is unverified
+// until confirmed with a code from /me/phone/code.
+func (s *ProfileService) Update(userID uint, req models.UpdateProfileRequest, ip string) (*models.User, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	var changes []models.ProfileChange
+	change := func(field, oldValue, newValue string) {
+		changes = append(changes, models.ProfileChange{
+			UserID:   userID,
+			Field:    field,
+			OldValue: oldValue,
+			NewValue: newValue,
+			Source:   "profile",
+			IP:       ip,
+		})
+	}
+
+	if req.FirstName != nil {
+		name, err := validName("first_name", *req.FirstName)
+		if err != nil {
+			return nil, err
+		}
+		if name != user.FirstName {
+			updates["first_name"] = name
+			change("first_name", user.FirstName, name)
+		}
+	}
+
+	if req.LastName != nil {
+		name, err := validName("last_name", *req.LastName)
+		if err != nil {
+			return nil, err
+		}
+		if name != user.LastName {
+			updates["last_name"] = name
+			change("last_name", user.LastName, name)
+		}
+	}
+
+	if req.PhoneNumber != nil {
+		number := strings.TrimSpace(*req.PhoneNumber)
+		if number != "" {
+			if number, err = s.phones.Normalize(number); err != nil {
+				return nil, err
+			}
+			if err := s.phones.checkAvailable(s.db, userID, number); err != nil {
+				return nil, err
+			}
+		}
+		if number != user.PhoneNumber {
+			updates["phone_number"] = pii.Seal("users", "phone_number", number)
+			updates["phone_index"] = pii.PhoneIndex(number)
+			updates["phone_verified"] = false
+			updates["phone_verified_at"] = nil
+			change("phone_number", user.PhoneNumber, number)
+		}
+	}
+
+	if req.DOB != nil {
+		var dob time.Time
+		if value := strings.TrimSpace(*req.DOB); value != "" {
+			if dob, err = time.Parse("2006-01-02", value); err != nil {
+				return nil, errors.New("invalid date format. Use YYYY-MM-DD")
+			}
+			if dob.After(time.Now()) || dob.Year() < 1900 {
+				return nil, errors.New("dob is out of range")
+			}
+		}
+		if !dob.Equal(user.DOB) {
+			updates["dob"] = pii.Seal("users", "dob", dob)
+			change("dob", formatDOB(user.DOB), formatDOB(dob))
+		}
+	}
+
+	if len(changes) == 0 {
+		return user, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			return errors.New("failed to update profile")
+		}
+		return recordProfileChanges(tx, changes...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.user(userID)
+}
+
+// ChangePassword replaces the password after checking the current one. Every
+// other session is signed out and every API key revoked; the caller should
+// hand out a new token for sessionID, the session making the change.
+func (s *ProfileService) ChangePassword(userID, sessionID uint, req models.ChangePasswordRequest, ip string) (*models.User, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guard.VerifyPassword(user, req.CurrentPassword, ip); err != nil {
+		return nil, err
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return nil, errors.New("new password must be different")
+	}
+	if err := s.passwords.Check(req.NewPassword, user.Email, user.FirstName, user.LastName); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"password":      hashedPassword,
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error; err != nil {
+			return errors.New("failed to update password")
+		}
+		if err := revokeSessions(tx, userID, sessionID, now); err != nil {
+			return err
+		}
+		if err := revokeAPIKeys(tx, s.guard, user, ip, "password change", now); err != nil {
+			return err
+		}
+
+		if err := recordProfileChanges(tx, models.ProfileChange{
+			UserID: userID,
+			Field:  "password",
+			Source: "password_change",
+			IP:     ip,
+		}); err != nil {
+			return err
+		}
+
+		return s.guard.Record(tx, "password_changed", &userID, normalizeEmail(user.Email), ip, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.user(userID)
+}
+
+// History lists the changes made to a user's profile, newest first
+func (s *ProfileService) History(userID uint) (*models.ProfileChangeListResponse, error) {
+	if _, err := s.user(userID); err != nil {
+		return nil, err
+	}
+
+	var changes []models.ProfileChange
+	if err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(200).
+		Find(&changes).Error; err != nil {
+		return nil, errors.New("failed to get profile changes")
+	}
+
+	return &models.ProfileChangeListResponse{
+		Changes: changes,
+		Count:   len(changes),
+	}, nil
+}
+
+// recordProfileChanges stores history entries. It must run inside the
+// transaction that makes the changes.
+func recordProfileChanges(tx *gorm.DB, changes ...models.ProfileChange) error {
+	if err := tx.Create(&changes).Error; err != nil {
+		return errors.New("failed to record profile change")
+	}
+	return nil
+}
+
+func (s *ProfileService) user(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
+
+func validName(field, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New(field + " must not be empty")
+	}
+	if len([]rune(name)) > maxNameLength {
+		return "", errors.New(field + " is too long")
+	}
+	return name, nil
+}
+
+func formatDOB(dob time.Time) string {
+	if dob.IsZero() {
+		return ""
+	}
+	return dob.Format("2006-01-02")
+}
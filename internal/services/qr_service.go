@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	qrScheme = "lbkpay"
+	qrHost   = "pay"
+)
+
+type QRService struct {
+	db         *gorm.DB
+	secret     []byte
+	defaultTTL time.Duration
+}
+
+func NewQRService(db *gorm.DB, secret []byte, defaultTTL time.Duration) *QRService {
+	return &QRService{db: db, secret: secret, defaultTTL: defaultTTL}
+}
+
+// CreatePayload builds the QR payload for receiving points. Without an amount
+// the code is static and only carries the LBK code; with one it is dynamic,
+// expires and is signed so the amount cannot be altered.
+func (s *QRService) CreatePayload(userID uint, req models.QRCodeRequest) (*models.QRPayloadResponse, error) {
+	var user models.User
+	if err := s.db.Select("id, lbk_code").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	values := url.Values{}
+	values.Set("to", user.LBKCode)
+
+	if req.Amount == 0 {
+		if req.Reference != "" {
+			return nil, errors.New("reference requires an amount")
+		}
+		return &models.QRPayloadResponse{
+			Payload: qrURL(values.Encode()),
+			Type:    "static",
+		}, nil
+	}
+
+	currency := NormalizeCurrency(req.Currency)
+	if err := RequireActive(s.db, currency); err != nil {
+		return nil, err
+	}
+
+	ttl := s.defaultTTL
+	if req.ExpiresInMinutes > 0 {
+		ttl = time.Duration(req.ExpiresInMinutes) * time.Minute
+	}
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	values.Set("amount", strconv.FormatUint(uint64(req.Amount), 10))
+	values.Set("exp", strconv.FormatInt(expiresAt.Unix(), 10))
+	if req.Reference != "" {
+		values.Set("ref", req.Reference)
+	}
+	if currency != models.DefaultCurrency {
+		values.Set("cur", currency)
+	}
+
+	// Encode sorts the keys, so the signed string can be rebuilt from a parsed payload
+	canonical := values.Encode()
+	payload := qrURL(canonical + "&sig=" + utils.SignPayload(canonical, s.secret))
+
+	return &models.QRPayloadResponse{
+		Payload:   payload,
+		Type:      "dynamic",
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// ParsePayload validates a scanned payload and returns the transfer it
+// describes, ready to be reviewed and sent to the transfer endpoint
+func (s *QRService) ParsePayload(userID uint, payload string) (*models.QRParseResponse, error) {
+	u, err := url.Parse(payload)
+	if err != nil || u.Scheme != qrScheme || u.Host != qrHost {
+		return nil, errors.New("invalid qr payload")
+	}
+
+	values := u.Query()
+	lbkCode := values.Get("to")
+	if lbkCode == "" {
+		return nil, errors.New("invalid qr payload")
+	}
+
+	response := &models.QRParseResponse{
+		Type:     "static",
+		Transfer: models.TransferRequest{ToLBKCode: lbkCode, Currency: models.DefaultCurrency},
+	}
+
+	signature := values.Get("sig")
+	if signature != "" || values.Has("amount") {
+		values.Del("sig")
+		if !utils.CheckPayloadSignature(values.Encode(), signature, s.secret) {
+			return nil, errors.New("invalid qr signature")
+		}
+
+		amount, err := strconv.ParseUint(values.Get("amount"), 10, 32)
+		if err != nil || amount == 0 {
+			return nil, errors.New("invalid qr payload")
+		}
+		exp, err := strconv.ParseInt(values.Get("exp"), 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid qr payload")
+		}
+		expiresAt := time.Unix(exp, 0)
+		if time.Now().After(expiresAt) {
+			return nil, errors.New("qr code expired")
+		}
+
+		response.Type = "dynamic"
+		response.Transfer.Amount = uint(amount)
+		response.Transfer.Message = values.Get("ref")
+		response.Transfer.Currency = NormalizeCurrency(values.Get("cur"))
+		response.AmountLocked = true
+		response.ExpiresAt = &expiresAt
+	}
+
+	var recipient models.User
+	if err := s.db.Select("id, lbk_code, first_name, last_name").Where("lbk_code = ? AND closed_at IS NULL", lbkCode).First(&recipient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recipient user not found")
+		}
+		return nil, errors.New("database error")
+	}
+	if recipient.ID == userID {
+		return nil, errors.New("cannot transfer points to yourself")
+	}
+
+	response.Recipient = models.UserSearchResponse{
+		LBKCode:   recipient.LBKCode,
+		FirstName: recipient.FirstName,
+		LastName:  recipient.LastName,
+	}
+
+	return response, nil
+}
+
+func qrURL(query string) string {
+	return qrScheme + "://" + qrHost + "?" + query
+}
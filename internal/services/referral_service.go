@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Free mail providers are shared by unrelated people, so a matching domain
+// there says nothing about the two accounts belonging to the same person
+var publicEmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"hotmail.com":    true,
+	"outlook.com":    true,
+	"live.com":       true,
+	"yahoo.com":      true,
+	"icloud.com":     true,
+	"proton.me":      true,
+}
+
+type ReferralService struct {
+	db        *gorm.DB
+	campaigns *CampaignService
+}
+
+func NewReferralService(db *gorm.DB, campaigns *CampaignService) *ReferralService {
+	return &ReferralService{db: db, campaigns: campaigns}
+}
+
+// NewCode returns a referral code that is not yet taken
+func (s *ReferralService) NewCode(tx *gorm.DB) (string, error) {
+	for i := 0; i < 5; i++ {
+		code, err := utils.GenerateReferralCode()
+		if err != nil {
+			return "", errors.New("failed to generate referral code")
+		}
+
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("referral_code = ?", code).Count(&taken).Error; err != nil {
+			return "", errors.New("database error")
+		}
+		if taken == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.New("failed to generate referral code")
+}
+
+// BackfillCodes gives a referral code to users created before referrals existed
+func (s *ReferralService) BackfillCodes() error {
+	var users []models.User
+	if err := s.db.Select("id").Where("referral_code IS NULL").Find(&users).Error; err != nil {
+		return errors.New("failed to load users")
+	}
+
+	for _, user := range users {
+		code, err := s.NewCode(s.db)
+		if err != nil {
+			return err
+		}
+		if err := s.db.Model(&user).Update("referral_code", code).Error; err != nil {
+			return errors.New("failed to set referral code")
+		}
+	}
+
+	return nil
+}
+
+// Attach records that referee signed up with code. Sign-ups that look like
+// the referrer inviting themselves are kept but rejected so they never pay out.
+// It must run inside tx.
+func (s *ReferralService) Attach(tx *gorm.DB, referee *models.User, code string) error {
+	var referrer models.User
+	if err := tx.Where("referral_code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&referrer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid referral code")
+		}
+		return errors.New("database error")
+	}
+
+	referral := models.Referral{
+		ReferrerID: referrer.ID,
+		RefereeID:  referee.ID,
+		Status:     "pending",
+	}
+	if reason := abuseReason(&referrer, referee); reason != "" {
+		referral.Status = "rejected"
+		referral.RejectReason = reason
+	}
+
+	if err := tx.Create(&referral).Error; err != nil {
+		return errors.New("failed to record referral")
+	}
+
+	return nil
+}
+
+// abuseReason reports why a referral looks like a self-referral, or "" if it does not
+func abuseReason(referrer, referee *models.User) string {
+	if referee.DeviceID != "" && referee.DeviceID == referrer.DeviceID {
+		return "same_device"
+	}
+
+	if domain := emailDomain(referee.Email); domain != "" && !publicEmailDomains[domain] && domain == emailDomain(referrer.Email) {
+		return "same_email_domain"
+	}
+
+	if referee.PhoneNumber != "" && referee.PhoneNumber == referrer.PhoneNumber {
+		return "same_phone"
+	}
+
+	return ""
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// Qualify pays the referral campaigns to both sides once the referee has
+// completed their qualifying action. It must run inside tx.
+func (s *ReferralService) Qualify(tx *gorm.DB, refereeID uint) error {
+	var referral models.Referral
+	if err := tx.Where("referee_id = ? AND status = ?", refereeID, "pending").First(&referral).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.New("database error")
+	}
+
+	now := time.Now()
+	referrerBonus, err := s.campaigns.Reward(tx, CampaignReferral, referral.ReferrerID, now)
+	if err != nil {
+		return err
+	}
+	refereeBonus, err := s.campaigns.Reward(tx, CampaignReferral, referral.RefereeID, now)
+	if err != nil {
+		return err
+	}
+
+	// Without a running referral campaign the referral still qualifies, it just pays nothing
+	status := "qualified"
+	if referrerBonus > 0 || refereeBonus > 0 {
+		status = "rewarded"
+	}
+
+	if err := tx.Model(&referral).Updates(map[string]interface{}{
+		"status":       status,
+		"qualified_at": now,
+	}).Error; err != nil {
+		return errors.New("failed to update referral")
+	}
+
+	return nil
+}
+
+func (s *ReferralService) GetReferrals(userID uint) (*models.ReferralsResponse, error) {
+	var user models.User
+	if err := s.db.Select("id, referral_code").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	var referrals []models.Referral
+	if err := s.db.Preload("Referee").
+		Where("referrer_id = ?", userID).
+		Order("created_at DESC").
+		Find(&referrals).Error; err != nil {
+		return nil, errors.New("failed to get referrals")
+	}
+
+	response := &models.ReferralsResponse{
+		Referrals: []models.ReferralSummary{},
+		Count:     len(referrals),
+	}
+	if user.ReferralCode != nil {
+		response.ReferralCode = *user.ReferralCode
+	}
+	for _, referral := range referrals {
+		response.Referrals = append(response.Referrals, models.ReferralSummary{
+			ID:          referral.ID,
+			RefereeName: shortName(referral.Referee.FirstName, referral.Referee.LastName),
+			Status:      referral.Status,
+			CreatedAt:   referral.CreatedAt,
+			QualifiedAt: referral.QualifiedAt,
+		})
+	}
+
+	return response, nil
+}
+
+// shortName renders a name as "First L." so referrers can recognise invitees
+// without seeing their full details
+func shortName(firstName, lastName string) string {
+	if lastName == "" {
+		return firstName
+	}
+	return firstName + " " + string([]rune(lastName)[:1]) + "."
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// A session's last-seen time and IP are written at most this often, not
+	// on every request
+	sessionTouchInterval = time.Minute
+
+	// Expired and revoked sessions are kept this long, e.g. for data exports
+	sessionRetention = 30 * 24 * time.Hour
+
+	maxDeviceLabelLength = 100
+	maxUserAgentLength   = 255
+)
+
+// SessionService tracks where users are logged in and lets them sign out a
+// device. Every JWT names its session, and JWTMiddleware refuses tokens of
+// revoked sessions.
+type SessionService struct {
+	db    *gorm.DB
+	guard *LoginGuard
+}
+
+func NewSessionService(db *gorm.DB, guard *LoginGuard) *SessionService {
+	return &SessionService{db: db, guard: guard}
+}
+
+// Start records a new login, lasting as long as the token issued for it
+func (s *SessionService) Start(userID uint, deviceLabel, ip, userAgent string) (*models.Session, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:      userID,
+		DeviceLabel: truncate(deviceLabel, maxDeviceLabelLength),
+		IP:          ip,
+		UserAgent:   truncate(userAgent, maxUserAgentLength),
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(utils.TokenTTL),
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, errors.New("failed to create session")
+	}
+	return &session, nil
+}
+
+// Touch checks that the session is still active and notes that it was just
+// used from ip
+func (s *SessionService) Touch(userID, sessionID uint, ip string) error {
+	now := time.Now()
+
+	var session models.Session
+	if err := s.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session revoked")
+		}
+		return errors.New("database error")
+	}
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return errors.New("session revoked")
+	}
+
+	if session.IP != ip || now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.db.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"ip":           ip,
+			"last_seen_at": now,
+		}).Error; err != nil {
+			return errors.New("database error")
+		}
+	}
+	return nil
+}
+
+// List returns the user's active sessions, most recently used first, marking
+// the one with currentID
+func (s *SessionService) List(userID, currentID uint) (*models.SessionListResponse, error) {
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC, id DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, errors.New("failed to get sessions")
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return &models.SessionListResponse{
+		Sessions: sessions,
+		Count:    len(sessions),
+	}, nil
+}
+
+// Revoke signs out one of the user's sessions. Revoking the current session
+// logs the caller out.
+func (s *SessionService) Revoke(userID, sessionID uint, ip string) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("database error")
+	}
+
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return errors.New("failed to revoke session")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("session not found")
+		}
+
+		return s.guard.Record(tx, "session_revoked", &userID, normalizeEmail(user.Email), ip, fmt.Sprintf("session %d", sessionID))
+	})
+}
+
+// PurgeExpired deletes sessions that expired longer ago than they are kept
+func (s *SessionService) PurgeExpired(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ?", now.Add(-sessionRetention)).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, errors.New("failed to purge sessions")
+	}
+	return result.RowsAffected, nil
+}
+
+// revokeSessions ends every session of the user but keepID, which is renewed
+// for a token issued now. It is called where token_version is bumped, so the
+// session list matches the tokens that still work. keepID may be 0.
+func revokeSessions(tx *gorm.DB, userID, keepID uint, now time.Time) error {
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", now).Error; err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	if keepID != 0 {
+		if err := tx.Model(&models.Session{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL", keepID, userID).
+			Update("expires_at", now.Add(utils.TokenTTL)).Error; err != nil {
+			return errors.New("failed to renew session")
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -3,19 +3,35 @@ package services
 import (
 	"errors"
 	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
+	"fiber-api/internal/utils"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type TransferService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	ledger    *PointLedger
+	campaigns *CampaignService
+	referrals *ReferralService
+	requests  *PaymentRequestService
+	pins      *PINService
+	phones    *PhoneService
 }
 
-func NewTransferService(db *gorm.DB) *TransferService {
-	return &TransferService{db: db}
+func NewTransferService(db *gorm.DB, ledger *PointLedger, campaigns *CampaignService, referrals *ReferralService, requests *PaymentRequestService, pins *PINService, phones *PhoneService) *TransferService {
+	return &TransferService{db: db, ledger: ledger, campaigns: campaigns, referrals: referrals, requests: requests, pins: pins, phones: phones}
 }
 
 func (s *TransferService) TransferPoints(fromUserID uint, req models.TransferRequest) (*models.TransferResponse, error) {
+	// High-value transfers need the transaction PIN. Checked before the
+	// transaction so wrong attempts count even though nothing is transferred.
+	if err := s.pins.Authorize(fromUserID, req.Amount, req.PIN); err != nil {
+		return nil, err
+	}
+
 	// Start transaction
 	tx := s.db.Begin()
 	defer func() {
@@ -31,20 +47,18 @@ func (s *TransferService) TransferPoints(fromUserID uint, req models.TransferReq
 		return nil, errors.New("failed to get sender information")
 	}
 
-	// Check if sender has enough points
-	if fromUser.PointBalance < req.Amount {
+	// Check the currency is one points can currently move in
+	currency := NormalizeCurrency(req.Currency)
+	if err := RequireActive(tx, currency); err != nil {
 		tx.Rollback()
-		return nil, errors.New("insufficient points")
+		return nil, err
 	}
 
 	// Find recipient user
-	var toUser models.User
-	if err := tx.Where("lbk_code = ?", req.ToLBKCode).First(&toUser).Error; err != nil {
+	toUser, alias, err := s.resolveRecipient(tx, req)
+	if err != nil {
 		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("recipient user not found")
-		}
-		return nil, errors.New("database error")
+		return nil, err
 	}
 
 	// Check if not transferring to self
@@ -53,24 +67,39 @@ func (s *TransferService) TransferPoints(fromUserID uint, req models.TransferReq
 		return nil, errors.New("cannot transfer points to yourself")
 	}
 
-	// Update balances
-	if err := tx.Model(&fromUser).Update("point_balance", fromUser.PointBalance-req.Amount).Error; err != nil {
+	// Integrations using a personal API key may only send up to its limit
+	if req.APIKeyID != nil {
+		if err := checkAPIKeyLimit(tx, *req.APIKeyID, req.Amount, time.Now()); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Update balances, spending the sender's oldest points first
+	if err := s.ledger.DebitCurrency(tx, fromUser.ID, currency, req.Amount); err != nil {
 		tx.Rollback()
+		if err.Error() == "insufficient points" {
+			return nil, err
+		}
 		return nil, errors.New("failed to update sender balance")
 	}
 
-	if err := tx.Model(&toUser).Update("point_balance", toUser.PointBalance+req.Amount).Error; err != nil {
+	if err := s.ledger.CreditCurrency(tx, toUser.ID, currency, req.Amount, "transfer"); err != nil {
 		tx.Rollback()
 		return nil, errors.New("failed to update recipient balance")
 	}
 
 	// Create transfer record
 	transfer := models.Transfer{
-		FromUserID: fromUser.ID,
-		ToUserID:   toUser.ID,
+		FromUserID: &fromUser.ID,
+		ToUserID:   &toUser.ID,
 		Amount:     req.Amount,
+		Currency:   currency,
 		Message:    req.Message,
+		ToAlias:    alias,
+		Type:       "transfer",
 		Status:     "completed",
+		APIKeyID:   req.APIKeyID,
 	}
 
 	if err := tx.Create(&transfer).Error; err != nil {
@@ -78,6 +107,31 @@ func (s *TransferService) TransferPoints(fromUserID uint, req models.TransferReq
 		return nil, errors.New("failed to create transfer record")
 	}
 
+	// Mark the sender's share of a payment request as paid
+	if req.PaymentRequestID != nil {
+		if err := s.requests.Settle(tx, *req.PaymentRequestID, fromUser.ID, toUser.ID, req.Amount, currency, transfer.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Reward the sender's first transfer, which is also the qualifying action for referrals
+	var sent int64
+	if err := tx.Model(&models.Transfer{}).Where("from_user_id = ? AND type = ?", fromUser.ID, "transfer").Count(&sent).Error; err != nil {
+		tx.Rollback()
+		return nil, errors.New("database error")
+	}
+	if sent == 1 {
+		if _, err := s.campaigns.Reward(tx, CampaignFirstTransfer, fromUser.ID, time.Now()); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := s.referrals.Qualify(tx, fromUser.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, errors.New("failed to complete transfer")
@@ -96,32 +150,92 @@ func (s *TransferService) TransferPoints(fromUserID uint, req models.TransferReq
 			FirstName: fromUser.FirstName,
 			LastName:  fromUser.LastName,
 		},
-		ToUser: struct {
-			LBKCode   string `json:"lbk_code"`
-			FirstName string `json:"first_name"`
-			LastName  string `json:"last_name"`
-		}{
-			LBKCode:   toUser.LBKCode,
-			FirstName: toUser.FirstName,
-			LastName:  toUser.LastName,
-		},
-		Amount: req.Amount,
-		Status: "completed",
+		Amount:   req.Amount,
+		Currency: currency,
+		Status:   "completed",
+	}
+
+	// Who owns a phone number or email is not revealed to the sender
+	if alias != "" {
+		response.ToUser.FirstName = utils.MaskName(toUser.FirstName)
+		response.ToUser.LastName = utils.MaskName(toUser.LastName)
+		response.ToUser.Alias = alias
+	} else {
+		response.ToUser.LBKCode = toUser.LBKCode
+		response.ToUser.FirstName = toUser.FirstName
+		response.ToUser.LastName = toUser.LastName
 	}
 
 	return response, nil
 }
 
-func (s *TransferService) GetTransferHistory(userID uint) (*models.TransferHistoryResponse, error) {
+// resolveRecipient finds the user a transfer is addressed to. For a phone
+// number or email it also returns the masked alias; only verified ones
+// resolve, so nobody receives points meant for an address they do not own.
+func (s *TransferService) resolveRecipient(tx *gorm.DB, req models.TransferRequest) (*models.User, string, error) {
+	given := 0
+	for _, recipient := range []string{req.ToLBKCode, req.ToPhone, req.ToEmail} {
+		if recipient != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, "", errors.New("exactly one of to_lbk_code, to_phone or to_email is required")
+	}
+
+	var query *gorm.DB
+	var alias string
+	switch {
+	case req.ToPhone != "":
+		number, err := s.phones.Normalize(req.ToPhone)
+		if err != nil {
+			return nil, "", err
+		}
+		query = tx.Where("phone_index = ? AND phone_verified = ?", pii.PhoneIndex(number), true)
+		alias = utils.MaskPhone(number)
+	case req.ToEmail != "":
+		email := strings.ToLower(strings.TrimSpace(req.ToEmail))
+		query = tx.Where("email_index = ? AND email_verified = ?", pii.EmailIndex(email), true)
+		alias = utils.MaskEmail(email)
+	default:
+		query = tx.Where("lbk_code = ?", req.ToLBKCode)
+	}
+
+	var user models.User
+	if err := query.Where("closed_at IS NULL").First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("recipient user not found")
+		}
+		return nil, "", errors.New("database error")
+	}
+	return &user, alias, nil
+}
+
+// GetTransferHistory lists the user's transfers, optionally only those in one currency
+func (s *TransferService) GetTransferHistory(userID uint, currency string) (*models.TransferHistoryResponse, error) {
+	query := s.db.Preload("FromUser").Preload("ToUser").Preload("Escrow").
+		Where("from_user_id = ? OR to_user_id = ?", userID, userID)
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+
 	var transfers []models.Transfer
-	if err := s.db.Preload("FromUser").Preload("ToUser").
-		Where("from_user_id = ? OR to_user_id = ?", userID, userID).
-		Order("created_at DESC").
+	if err := query.Order("created_at DESC").
 		Limit(50). // Limit to last 50 transfers
 		Find(&transfers).Error; err != nil {
 		return nil, errors.New("failed to get transfer history")
 	}
 
+	// Recipients the user addressed by phone or email stay masked, as in the transfer response
+	for i, transfer := range transfers {
+		if transfer.ToAlias != "" && transfer.ToUser != nil && transfer.FromUserID != nil && *transfer.FromUserID == userID {
+			transfers[i].ToUser = &models.User{
+				FirstName: utils.MaskName(transfer.ToUser.FirstName),
+				LastName:  utils.MaskName(transfer.ToUser.LastName),
+			}
+		}
+	}
+
 	return &models.TransferHistoryResponse{
 		Transfers: transfers,
 		Count:     len(transfers),
@@ -3,27 +3,47 @@ package services
 import (
 	"errors"
 	"fiber-api/internal/models"
+	"fiber-api/internal/pii"
 	"fiber-api/internal/utils"
+	"log"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	campaigns *CampaignService
+	referrals *ReferralService
+	phones    *PhoneService
+	passwords *PasswordPolicy
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(db *gorm.DB, campaigns *CampaignService, referrals *ReferralService, phones *PhoneService, passwords *PasswordPolicy) *UserService {
+	return &UserService{db: db, campaigns: campaigns, referrals: referrals, phones: phones, passwords: passwords}
 }
 
 func (s *UserService) CreateUser(req models.RegisterRequest) (*models.User, error) {
 	// Check if user already exists
 	var existingUser models.User
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := s.db.Where("email_index = ?", pii.EmailIndex(req.Email)).First(&existingUser).Error; err == nil {
 		return nil, errors.New("user already exists")
 	}
 
+	// Store phone numbers in E.164; the number stays unverified until confirmed by SMS
+	phoneNumber := req.PhoneNumber
+	if phoneNumber != "" {
+		var err error
+		if phoneNumber, err = s.phones.Normalize(phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.passwords.Check(req.Password, req.Email, req.FirstName, req.LastName); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -41,18 +61,51 @@ func (s *UserService) CreateUser(req models.RegisterRequest) (*models.User, erro
 
 	// Create user
 	user := models.User{
-		Email:        req.Email,
-		Password:     hashedPassword,
-		FirstName:    req.FirstName,
-		LastName:     req.LastName,
-		PhoneNumber:  req.PhoneNumber,
-		DOB:          dob,
-		LBKCode:      utils.GenerateLBKCode(),
-		PointBalance: 1000, // Give new users 1000 points to start
+		Email:       req.Email,
+		EmailIndex:  pii.EmailIndex(req.Email),
+		Password:    hashedPassword,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		PhoneNumber: phoneNumber,
+		PhoneIndex:  pii.PhoneIndex(phoneNumber),
+		DOB:         dob,
+		LBKCode:     utils.GenerateLBKCode(),
+		DeviceID:    req.DeviceID,
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
-		return nil, errors.New("failed to create user")
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		referralCode, err := s.referrals.NewCode(tx)
+		if err != nil {
+			return err
+		}
+		user.ReferralCode = &referralCode
+
+		if err := tx.Create(&user).Error; err != nil {
+			return errors.New("failed to create user")
+		}
+
+		// Every user holds the default currency from the start
+		if err := tx.Create(&models.Wallet{UserID: user.ID, Currency: models.DefaultCurrency}).Error; err != nil {
+			return errors.New("failed to create wallet")
+		}
+
+		// Link the user to whoever invited them
+		if req.ReferralCode != "" {
+			if err := s.referrals.Attach(tx, &user, req.ReferralCode); err != nil {
+				return err
+			}
+		}
+
+		// Pay out any running sign-up campaigns
+		bonus, err := s.campaigns.Reward(tx, CampaignSignup, user.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		user.PointBalance = bonus
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &user, nil
@@ -60,8 +113,10 @@ func (s *UserService) CreateUser(req models.RegisterRequest) (*models.User, erro
 
 func (s *UserService) AuthenticateUser(req models.LoginRequest) (*models.User, error) {
 	var user models.User
-	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := s.db.Where("email_index = ?", pii.EmailIndex(req.Email)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Spend as long as a real password check so response times do not reveal registered emails
+			utils.CheckPassword(req.Password, dummyPasswordHash())
 			return nil, errors.New("invalid credentials")
 		}
 		return nil, errors.New("database error")
@@ -72,9 +127,31 @@ func (s *UserService) AuthenticateUser(req models.LoginRequest) (*models.User, e
 		return nil, errors.New("invalid credentials")
 	}
 
+	// Upgrade hashes made with an older algorithm or parameters while the password is at hand
+	if utils.PasswordNeedsRehash(user.Password) {
+		s.rehashPassword(&user, req.Password)
+	}
+
 	return &user, nil
 }
 
+// rehashPassword stores a new hash of password unless the password changed
+// meanwhile. A failure is only logged; the next login tries again.
+func (s *UserService) rehashPassword(user *models.User, password string) {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+	if err := s.db.Model(&models.User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hash).Error; err != nil {
+		log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hash
+}
+
 func (s *UserService) GetUserByID(userID uint) (*models.User, error) {
 	var user models.User
 	if err := s.db.First(&user, userID).Error; err != nil {
@@ -86,9 +163,21 @@ func (s *UserService) GetUserByID(userID uint) (*models.User, error) {
 	return &user, nil
 }
 
+// TokenVersion returns the version a user's JWTs must carry to be accepted
+func (s *UserService) TokenVersion(userID uint) (int, error) {
+	var versions []int
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Pluck("token_version", &versions).Error; err != nil {
+		return 0, errors.New("database error")
+	}
+	if len(versions) == 0 {
+		return 0, errors.New("user not found")
+	}
+	return versions[0], nil
+}
+
 func (s *UserService) SearchUserByLBK(lbkCode string) (*models.User, error) {
 	var user models.User
-	if err := s.db.Select("lbk_code, first_name, last_name").Where("lbk_code = ?", lbkCode).First(&user).Error; err != nil {
+	if err := s.db.Select("lbk_code, first_name, last_name").Where("lbk_code = ? AND closed_at IS NULL", lbkCode).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
@@ -96,3 +185,31 @@ func (s *UserService) SearchUserByLBK(lbkCode string) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// PromoteAdmins grants the admin role to the users with the given emails
+func (s *UserService) PromoteAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	indexes := make([]string, len(emails))
+	for i, email := range emails {
+		indexes[i] = pii.EmailIndex(email)
+	}
+	if err := s.db.Model(&models.User{}).Where("email_index IN ?", indexes).Update("role", "admin").Error; err != nil {
+		return errors.New("failed to promote admins")
+	}
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is a hash of no real password, computed with the same cost as real ones
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword("not a real password")
+	})
+	return dummyHash
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"fiber-api/internal/utils"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Admins print at most this many vouchers per request
+const maxVoucherBatch = 100
+
+// Most points one batch may hold in total, like the largest conversion. Point
+// columns are signed 64-bit in SQLite, so larger amounts would wrap there too.
+const maxVoucherBatchTotal = math.MaxUint32
+
+type VoucherService struct {
+	db        *gorm.DB
+	ledger    *PointLedger
+	campaigns *CampaignService
+	pins      *PINService
+	validity  time.Duration
+	maxDays   int
+}
+
+func NewVoucherService(db *gorm.DB, ledger *PointLedger, campaigns *CampaignService, pins *PINService, validity time.Duration, maxDays int) *VoucherService {
+	return &VoucherService{db: db, ledger: ledger, campaigns: campaigns, pins: pins, validity: validity, maxDays: maxDays}
+}
+
+// Create locks points from the issuer's wallet behind a new voucher code
+func (s *VoucherService) Create(issuerID uint, req models.CreateVoucherRequest) (*models.VoucherCreatedResponse, error) {
+	if err := s.checkExpiry(req.ExpiresInDays); err != nil {
+		return nil, err
+	}
+	if err := s.pins.Authorize(issuerID, req.Amount, req.PIN); err != nil {
+		return nil, err
+	}
+
+	currency := NormalizeCurrency(req.Currency)
+
+	var created *models.VoucherCreatedResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := RequireActive(tx, currency); err != nil {
+			return err
+		}
+
+		if err := s.ledger.DebitCurrency(tx, issuerID, currency, req.Amount); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = s.newVoucher(tx, issuerID, "wallet", req.Amount, currency, req.Message, req.ExpiresInDays)
+		if err != nil {
+			return err
+		}
+
+		entry := models.Transfer{
+			FromUserID: &issuerID,
+			Amount:     req.Amount,
+			Currency:   currency,
+			Message:    "Voucher " + created.Voucher.CodeHint,
+			Type:       "voucher",
+			Status:     "completed",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Issue prints a batch of vouchers paid from the campaign account
+func (s *VoucherService) Issue(adminID uint, req models.IssueVouchersRequest) (*models.VoucherBatchResponse, error) {
+	count := req.Count
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 || count > maxVoucherBatch {
+		return nil, errors.New("count must be between 1 and 100")
+	}
+	if err := s.checkExpiry(req.ExpiresInDays); err != nil {
+		return nil, err
+	}
+	// Checked by division, since the product itself could wrap around
+	if req.Amount > maxVoucherBatchTotal/uint(count) {
+		return nil, errors.New("amount too large for the batch")
+	}
+
+	response := &models.VoucherBatchResponse{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.campaigns.Withdraw(tx, req.Amount*uint(count)); err != nil {
+			return err
+		}
+
+		for i := 0; i < count; i++ {
+			created, err := s.newVoucher(tx, adminID, "campaign", req.Amount, models.DefaultCurrency, req.Message, req.ExpiresInDays)
+			if err != nil {
+				return err
+			}
+			response.Vouchers = append(response.Vouchers, *created)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.Count = len(response.Vouchers)
+	return response, nil
+}
+
+// checkExpiry rejects lifetimes longer than the configured maximum
+func (s *VoucherService) checkExpiry(expiresInDays int) error {
+	if expiresInDays > s.maxDays {
+		return fmt.Errorf("expires_in_days must be at most %d", s.maxDays)
+	}
+	return nil
+}
+
+func (s *VoucherService) newVoucher(tx *gorm.DB, issuerID uint, funding string, amount uint, currency, message string, expiresInDays int) (*models.VoucherCreatedResponse, error) {
+	code, err := utils.GenerateVoucherCode()
+	if err != nil {
+		return nil, errors.New("failed to generate voucher code")
+	}
+
+	expiresAt := time.Now().Add(s.validity)
+	if expiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, expiresInDays)
+	}
+
+	voucher := models.Voucher{
+		CodeHash:  utils.HashToken(utils.NormalizeCode(code)),
+		CodeHint:  code[len(code)-4:],
+		IssuerID:  issuerID,
+		Funding:   funding,
+		Amount:    amount,
+		Currency:  currency,
+		Message:   message,
+		Status:    "active",
+		ExpiresAt: expiresAt,
+	}
+	if err := tx.Create(&voucher).Error; err != nil {
+		return nil, errors.New("failed to create voucher")
+	}
+
+	return &models.VoucherCreatedResponse{Code: code, Voucher: voucher}, nil
+}
+
+// Redeem credits the voucher's value to the user. Each voucher can be redeemed once.
+func (s *VoucherService) Redeem(userID uint, code string) (*models.Voucher, error) {
+	var voucher models.Voucher
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		hash := utils.HashToken(utils.NormalizeCode(code))
+		if err := tx.Where("code_hash = ?", hash).First(&voucher).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("voucher not found")
+			}
+			return errors.New("database error")
+		}
+
+		now := time.Now()
+		switch {
+		case voucher.Status == "redeemed":
+			return errors.New("voucher already redeemed")
+		case voucher.Status == "expired", !now.Before(voucher.ExpiresAt):
+			return errors.New("voucher expired")
+		}
+
+		// Guard against two users redeeming the same code at once
+		result := tx.Model(&models.Voucher{}).
+			Where("id = ? AND status = ?", voucher.ID, "active").
+			Updates(map[string]interface{}{
+				"status":      "redeemed",
+				"redeemed_by": userID,
+				"redeemed_at": now,
+			})
+		if result.Error != nil {
+			return errors.New("failed to update voucher")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("voucher already redeemed")
+		}
+
+		if err := s.ledger.CreditCurrency(tx, userID, voucher.Currency, voucher.Amount, "voucher"); err != nil {
+			return err
+		}
+
+		message := voucher.Message
+		if message == "" {
+			message = "Voucher " + voucher.CodeHint
+		}
+		entry := models.Transfer{
+			ToUserID: &userID,
+			Amount:   voucher.Amount,
+			Currency: voucher.Currency,
+			Message:  message,
+			Type:     "voucher",
+			Status:   "completed",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		voucher.Status = "redeemed"
+		voucher.RedeemedBy = &userID
+		voucher.RedeemedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &voucher, nil
+}
+
+// List returns the vouchers the user bought
+func (s *VoucherService) List(issuerID uint) (*models.VoucherListResponse, error) {
+	var vouchers []models.Voucher
+	if err := s.db.Where("issuer_id = ? AND funding = ?", issuerID, "wallet").
+		Order("created_at DESC").
+		Limit(50).
+		Find(&vouchers).Error; err != nil {
+		return nil, errors.New("failed to get vouchers")
+	}
+
+	return &models.VoucherListResponse{
+		Vouchers: vouchers,
+		Count:    len(vouchers),
+	}, nil
+}
+
+func (s *VoucherService) ListAll(status string) (*models.VoucherListResponse, error) {
+	query := s.db.Order("created_at DESC").Limit(200)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var vouchers []models.Voucher
+	if err := query.Find(&vouchers).Error; err != nil {
+		return nil, errors.New("failed to get vouchers")
+	}
+
+	return &models.VoucherListResponse{
+		Vouchers: vouchers,
+		Count:    len(vouchers),
+	}, nil
+}
+
+// ExpireVouchers refunds every unredeemed voucher past its expiry to whoever
+// paid for it: the issuer's wallet or the campaign account
+func (s *VoucherService) ExpireVouchers(now time.Time) (int, error) {
+	var vouchers []models.Voucher
+	if err := s.db.Where("status = ? AND expires_at <= ?", "active", now).Find(&vouchers).Error; err != nil {
+		return 0, errors.New("failed to load expired vouchers")
+	}
+
+	expired := 0
+	for _, voucher := range vouchers {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return s.expire(tx, voucher)
+		}); err != nil {
+			log.Printf("Failed to expire voucher %d: %v", voucher.ID, err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+func (s *VoucherService) expire(tx *gorm.DB, voucher models.Voucher) error {
+	result := tx.Model(&models.Voucher{}).
+		Where("id = ? AND status = ?", voucher.ID, "active").
+		Update("status", "expired")
+	if result.Error != nil {
+		return errors.New("failed to update voucher")
+	}
+	if result.RowsAffected == 0 {
+		// Redeemed in the meantime
+		return nil
+	}
+
+	note := "Expired voucher " + voucher.CodeHint
+	if voucher.Funding == "campaign" {
+		return s.campaigns.Return(tx, voucher.Amount, note)
+	}
+
+	if err := s.ledger.CreditCurrency(tx, voucher.IssuerID, voucher.Currency, voucher.Amount, "refund"); err != nil {
+		return err
+	}
+
+	entry := models.Transfer{
+		ToUserID: &voucher.IssuerID,
+		Amount:   voucher.Amount,
+		Currency: voucher.Currency,
+		Message:  note,
+		Type:     "refund",
+		Status:   "completed",
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return errors.New("failed to create transfer record")
+	}
+
+	return nil
+}
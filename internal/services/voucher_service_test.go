@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fiber-api/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIssueRejectsOverflowingBatchTotal(t *testing.T) {
+	db := newTestDB(t)
+	ledger := NewPointLedger(0)
+	campaigns := NewCampaignService(db, ledger)
+	if err := campaigns.Seed(0, 1000); err != nil {
+		t.Fatalf("Seed: %v", err)
+	}
+	vouchers := NewVoucherService(db, ledger, campaigns, nil, 24*time.Hour, 30)
+
+	tests := []struct {
+		name    string
+		amount  uint
+		count   int
+		wantErr string
+	}{
+		{"total wraps to zero", math.MaxUint/2 + 1, 2, "amount too large for the batch"},
+		{"total wraps past zero", math.MaxUint/3 + 1, 3, "amount too large for the batch"},
+		{"amount beyond 64-bit signed", math.MaxUint, 1, "amount too large for the batch"},
+		{"total above the cap", math.MaxUint32/2 + 1, 2, "amount too large for the batch"},
+		{"largest total is unaffordable", math.MaxUint32, 1, "insufficient campaign funds"},
+		{"total above the funds", 400, 3, "insufficient campaign funds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := vouchers.Issue(1, models.IssueVouchersRequest{Amount: tt.amount, Count: tt.count})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	var balance uint
+	db.Model(&models.CampaignAccount{}).Where("id = ?", 1).Select("balance").Scan(&balance)
+	var issued int64
+	db.Model(&models.Voucher{}).Count(&issued)
+	if balance != 1000 || issued != 0 {
+		t.Errorf("balance %d and %d vouchers after rejected batches, want 1000 and 0", balance, issued)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fiber-api/internal/models"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+type WalletService struct {
+	db     *gorm.DB
+	ledger *PointLedger
+}
+
+func NewWalletService(db *gorm.DB, ledger *PointLedger) *WalletService {
+	return &WalletService{db: db, ledger: ledger}
+}
+
+// Seed creates the default currency on first start
+func (s *WalletService) Seed() error {
+	currency := models.Currency{Code: models.DefaultCurrency, Name: "Loyalty points", Active: true}
+	if err := s.db.Where("code = ?", currency.Code).FirstOrCreate(&currency).Error; err != nil {
+		return errors.New("failed to seed default currency")
+	}
+	return nil
+}
+
+// BackfillWallets moves balances held before wallets existed into each
+// user's default currency wallet
+func (s *WalletService) BackfillWallets() error {
+	var users []models.User
+	if err := s.db.Select("id, point_balance").
+		Where("NOT EXISTS (SELECT 1 FROM wallets WHERE wallets.user_id = users.id AND wallets.currency = ?)", models.DefaultCurrency).
+		Find(&users).Error; err != nil {
+		return errors.New("failed to load users")
+	}
+
+	for _, user := range users {
+		wallet := models.Wallet{
+			UserID:   user.ID,
+			Currency: models.DefaultCurrency,
+			Balance:  user.PointBalance,
+		}
+		if err := s.db.Create(&wallet).Error; err != nil {
+			return errors.New("failed to create wallet")
+		}
+	}
+
+	return nil
+}
+
+// NormalizeCurrency turns user input into a currency code, defaulting to the
+// loyalty points program when empty
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return models.DefaultCurrency
+	}
+	return code
+}
+
+// RequireActive checks that points can currently move in the currency
+func RequireActive(tx *gorm.DB, code string) error {
+	var currency models.Currency
+	if err := tx.Where("code = ? AND active = ?", code, true).First(&currency).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("unknown currency")
+		}
+		return errors.New("database error")
+	}
+	return nil
+}
+
+// GetBalance returns the user's balance in currency; a wallet that was never
+// opened simply holds nothing
+func (s *WalletService) GetBalance(userID uint, currency string) (uint, error) {
+	var known int64
+	if err := s.db.Model(&models.Currency{}).Where("code = ?", currency).Count(&known).Error; err != nil {
+		return 0, errors.New("database error")
+	}
+	if known == 0 {
+		return 0, errors.New("unknown currency")
+	}
+
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ? AND currency = ?", userID, currency).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, errors.New("database error")
+	}
+	return wallet.Balance, nil
+}
+
+func (s *WalletService) GetWallets(userID uint) (*models.WalletsResponse, error) {
+	var wallets []models.WalletBalance
+	if err := s.db.Table("wallets").
+		Select("wallets.currency, currencies.name, wallets.balance").
+		Joins("JOIN currencies ON currencies.code = wallets.currency").
+		Where("wallets.user_id = ?", userID).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "wallets.currency <> ?, wallets.currency",
+			Vars:               []interface{}{models.DefaultCurrency},
+			WithoutParentheses: true,
+		}}).
+		Scan(&wallets).Error; err != nil {
+		return nil, errors.New("failed to get wallets")
+	}
+
+	return &models.WalletsResponse{
+		Wallets: wallets,
+		Count:   len(wallets),
+	}, nil
+}
+
+func (s *WalletService) ListCurrencies(includeInactive bool) (*models.CurrencyListResponse, error) {
+	query := s.db.Order("code")
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+
+	var currencies []models.Currency
+	if err := query.Find(&currencies).Error; err != nil {
+		return nil, errors.New("failed to get currencies")
+	}
+
+	return &models.CurrencyListResponse{
+		Currencies: currencies,
+		Count:      len(currencies),
+	}, nil
+}
+
+func (s *WalletService) CreateCurrency(req models.CreateCurrencyRequest) (*models.Currency, error) {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if !currencyCodePattern.MatchString(code) {
+		return nil, errors.New("invalid currency code")
+	}
+
+	var existing models.Currency
+	if err := s.db.Where("code = ?", code).First(&existing).Error; err == nil {
+		return nil, errors.New("currency already exists")
+	}
+
+	currency := models.Currency{Code: code, Name: req.Name, Active: true}
+	if err := s.db.Create(&currency).Error; err != nil {
+		return nil, errors.New("failed to create currency")
+	}
+
+	return &currency, nil
+}
+
+func (s *WalletService) UpdateCurrency(code string, req models.UpdateCurrencyRequest) (*models.Currency, error) {
+	var currency models.Currency
+	if err := s.db.Where("code = ?", strings.ToUpper(code)).First(&currency).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("currency not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Active != nil {
+		if !*req.Active && currency.Code == models.DefaultCurrency {
+			return nil, errors.New("the default currency cannot be deactivated")
+		}
+		updates["active"] = *req.Active
+	}
+
+	if err := s.db.Model(&currency).Updates(updates).Error; err != nil {
+		return nil, errors.New("failed to update currency")
+	}
+
+	if err := s.db.Where("code = ?", currency.Code).First(&currency).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+
+	return &currency, nil
+}
+
+// Issue credits newly created points of a currency to a user on behalf of an
+// admin, e.g. event tokens handed out at a venue
+func (s *WalletService) Issue(code string, req models.IssuePointsRequest) (*models.Transfer, error) {
+	currency := NormalizeCurrency(code)
+
+	var entry models.Transfer
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := RequireActive(tx, currency); err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.Where("lbk_code = ? AND closed_at IS NULL", req.ToLBKCode).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("recipient user not found")
+			}
+			return errors.New("database error")
+		}
+
+		if err := s.ledger.CreditCurrency(tx, user.ID, currency, req.Amount, "issue"); err != nil {
+			return err
+		}
+
+		entry = models.Transfer{
+			ToUserID: &user.ID,
+			Amount:   req.Amount,
+			Currency: currency,
+			Message:  req.Message,
+			Type:     "issue",
+			Status:   "completed",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("failed to create transfer record")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"log"
+)
+
+// SMSSender delivers a text message to a phone number in E.164 format
+type SMSSender interface {
+	Send(to, message string) error
+}
+
+// LogSender writes text messages to the server log instead of sending them.
+// Meant for development and tests until an SMS gateway is configured.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(to, message string) error {
+	log.Printf("SMS to %s: %s", to, message)
+	return nil
+}
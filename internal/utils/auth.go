@@ -1,24 +1,13 @@
 package utils
 
 import (
+	"crypto/rand"
 	"fmt"
+	"math/big"
+	"strings"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-// Hash password
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
-}
-
-// Check password
-func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
 // Generate LBK code
 func GenerateLBKCode() string {
 	// Generate a random 6-digit number for LBK code
@@ -26,3 +15,50 @@ func GenerateLBKCode() string {
 	timestamp := time.Now().Unix()
 	return fmt.Sprintf("LBK%06d", timestamp%1000000)
 }
+
+// Generate referral code
+func GenerateReferralCode() (string, error) {
+	// Skip characters that are easily confused when read aloud or typed (0/O, 1/I/L)
+	const alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	return RandomString(alphabet, 8)
+}
+
+// RandomString returns a cryptographically random string of length n drawn from alphabet
+func RandomString(alphabet string, n int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[idx.Int64()]
+	}
+	return string(b), nil
+}
+
+// Generate voucher code, four groups of four characters
+func GenerateVoucherCode() (string, error) {
+	const alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	code, err := RandomString(alphabet, 16)
+	if err != nil {
+		return "", err
+	}
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// NormalizeCode drops separators and case so a printed code matches however it was typed
+func NormalizeCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// Generate 2FA recovery code, two groups of five characters
+func GenerateRecoveryCode() (string, error) {
+	const alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	code, err := RandomString(alphabet, 10)
+	if err != nil {
+		return "", err
+	}
+	return code[:5] + "-" + code[5:], nil
+}
@@ -6,20 +6,55 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// How long a JWT, and the session it belongs to, stays valid
+const TokenTTL = 24 * time.Hour
+
 // JWT Claims
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
+	// Purpose marks restricted tokens, e.g. "mfa" for a login waiting for its
+	// second factor. Only tokens without a purpose grant API access.
+	Purpose string `json:"purpose,omitempty"`
+	// Version is the user's token version at issue time. Tokens from an older
+	// version are rejected, which signs the user out everywhere.
+	Version int `json:"ver"`
+	// SessionID is the login session the token belongs to. Tokens issued
+	// before sessions were tracked have none.
+	SessionID uint `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // Generate JWT token
-func GenerateToken(userID uint, email string, jwtSecret []byte) (string, error) {
+func GenerateToken(userID uint, email, role string, version int, sessionID uint, jwtSecret []byte) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		Version:   version,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// Generate the short-lived token that stands for a half-finished login until
+// the second factor is checked. nonce becomes the token ID, so the token can
+// be used up once the login completes.
+func GenerateMFAToken(userID uint, version int, nonce string, jwtSecret []byte, ttl time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:  userID,
+		Purpose: "mfa",
+		Version: version,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        nonce,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
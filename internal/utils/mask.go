@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskEmail hides most of the local part, e.g. "somchai@example.com" becomes "s***@example.com"
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return firstRune(email[:at]) + "***" + email[at:]
+}
+
+// MaskPhone keeps the country code prefix and the last four digits of an
+// E.164 number, e.g. "+66812345678" becomes "+66*****5678"
+func MaskPhone(number string) string {
+	if len(number) < 8 {
+		return "***"
+	}
+	return number[:3] + strings.Repeat("*", len(number)-7) + number[len(number)-4:]
+}
+
+// MaskName keeps only the initial, e.g. "Somchai" becomes "S***"
+func MaskName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return firstRune(name) + "***"
+}
+
+func firstRune(s string) string {
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size]
+}
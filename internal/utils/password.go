@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// PasswordParams controls how new password hashes are made. Hashes made with
+// other settings still verify, and PasswordNeedsRehash reports them.
+type PasswordParams struct {
+	Algorithm   string // argon2id or bcrypt
+	Memory      uint32 // argon2id memory in KiB
+	Iterations  uint32 // argon2id passes over the memory
+	Parallelism uint8  // argon2id lanes
+	BcryptCost  int
+	Concurrency int // Hashes computed at once; further calls wait for a free slot
+}
+
+// DefaultPasswordParams follow the OWASP recommendation for argon2id
+var DefaultPasswordParams = PasswordParams{
+	Algorithm:   "argon2id",
+	Memory:      19456,
+	Iterations:  2,
+	Parallelism: 1,
+	BcryptCost:  12,
+	Concurrency: 4,
+}
+
+var passwordParams = DefaultPasswordParams
+
+// hashSlots bounds the hashes computed at once. Each argon2id hash holds
+// Memory KiB until it is done, so without it a burst of logins could take
+// the memory of the whole machine.
+var hashSlots = make(chan struct{}, DefaultPasswordParams.Concurrency)
+
+// acquireHashSlot waits for a free hash slot and returns the func that frees it
+func acquireHashSlot() func() {
+	slots := hashSlots
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// SetPasswordParams changes the settings of new hashes. It is meant to be
+// called once at startup.
+func SetPasswordParams(p PasswordParams) error {
+	switch p.Algorithm {
+	case "argon2id":
+		if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 {
+			return errors.New("invalid argon2id parameters")
+		}
+	case "bcrypt":
+		if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+			return errors.New("invalid bcrypt cost")
+		}
+	default:
+		return fmt.Errorf("unknown password hash algorithm %q", p.Algorithm)
+	}
+	if p.Concurrency < 1 {
+		return errors.New("password hash concurrency must be at least 1")
+	}
+	passwordParams = p
+	hashSlots = make(chan struct{}, p.Concurrency)
+	return nil
+}
+
+// Hash password with the configured algorithm. argon2id hashes use the PHC
+// string format, $argon2id$v=19$m=<KiB>,t=<iterations>,p=<lanes>$<salt>$<hash>,
+// so the algorithm and its parameters travel with the hash.
+func HashPassword(password string) (string, error) {
+	p := passwordParams
+	release := acquireHashSlot()
+	defer release()
+
+	if p.Algorithm == "bcrypt" {
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		return string(bytes), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Check password against an argon2id or bcrypt hash
+func CheckPassword(password, hash string) bool {
+	release := acquireHashSlot()
+	defer release()
+
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		return err == nil
+	}
+
+	h, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1
+}
+
+// PasswordNeedsRehash reports whether hash was made with another algorithm or
+// other parameters than new hashes are. The caller should hash the password
+// again while it has it, e.g. right after a successful login.
+func PasswordNeedsRehash(hash string) bool {
+	p := passwordParams
+	if p.Algorithm == "bcrypt" {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != p.BcryptCost
+	}
+
+	h, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	return h.memory != p.Memory || h.iterations != p.Iterations || h.parallelism != p.Parallelism ||
+		len(h.salt) != argon2SaltLength || len(h.key) != argon2KeyLength
+}
+
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func parseArgon2Hash(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.New("unsupported argon2 version")
+	}
+
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, errors.New("invalid argon2 parameters")
+	}
+	if h.iterations < 1 || h.parallelism < 1 {
+		return nil, errors.New("invalid argon2 parameters")
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.New("invalid argon2 salt")
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, errors.New("invalid argon2 hash")
+	}
+	return &h, nil
+}
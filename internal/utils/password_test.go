@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap settings so the tests run fast
+var testArgon2Params = PasswordParams{Algorithm: "argon2id", Memory: 64, Iterations: 1, Parallelism: 1, BcryptCost: bcrypt.MinCost, Concurrency: 4}
+
+// usePasswordParams switches to p for the rest of the test
+func usePasswordParams(t *testing.T, p PasswordParams) {
+	t.Helper()
+	previous, previousSlots := passwordParams, hashSlots
+	if err := SetPasswordParams(p); err != nil {
+		t.Fatalf("SetPasswordParams: %v", err)
+	}
+	t.Cleanup(func() { passwordParams, hashSlots = previous, previousSlots })
+}
+
+func testBcryptParams() PasswordParams {
+	p := testArgon2Params
+	p.Algorithm = "bcrypt"
+	return p
+}
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		params PasswordParams
+		prefix string
+	}{
+		{"argon2id", testArgon2Params, "$argon2id$v=19$m=64,t=1,p=1$"},
+		{"bcrypt", testBcryptParams(), "$2a$04$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasswordParams(t, tt.params)
+
+			hash, err := HashPassword("correct horse")
+			if err != nil {
+				t.Fatalf("HashPassword: %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash %q lacks prefix %q", hash, tt.prefix)
+			}
+			if !CheckPassword("correct horse", hash) {
+				t.Error("the password does not match its own hash")
+			}
+			for _, wrong := range []string{"correct horsE", "correct horse ", ""} {
+				if CheckPassword(wrong, hash) {
+					t.Errorf("%q matches the hash of another password", wrong)
+				}
+			}
+			if PasswordNeedsRehash(hash) {
+				t.Error("a fresh hash needs a rehash")
+			}
+
+			again, err := HashPassword("correct horse")
+			if err != nil {
+				t.Fatalf("HashPassword: %v", err)
+			}
+			if again == hash {
+				t.Error("hashing twice gave the same hash; the salt is not random")
+			}
+		})
+	}
+}
+
+func TestCheckPasswordBcryptFallback(t *testing.T) {
+	usePasswordParams(t, testArgon2Params)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{"bcrypt hash", "correct horse", string(legacy), true},
+		{"bcrypt hash, wrong password", "wrong horse", string(legacy), false},
+		{"not a hash", "correct horse", "correct horse", false},
+		{"empty hash", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(tt.password, tt.hash); got != tt.want {
+				t.Errorf("CheckPassword = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !PasswordNeedsRehash(string(legacy)) {
+		t.Error("a bcrypt hash does not need a rehash with argon2id configured")
+	}
+}
+
+func TestParseArgon2Hash(t *testing.T) {
+	usePasswordParams(t, testArgon2Params)
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	parts := strings.Split(hash, "$") // "", argon2id, v=19, m=,t=,p=, salt, key
+	with := func(i int, part string) string {
+		changed := append([]string(nil), parts...)
+		changed[i] = part
+		return strings.Join(changed, "$")
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{"valid", hash, false},
+		{"argon2i", with(1, "argon2i"), true},
+		{"other version", with(2, "v=16"), true},
+		{"version missing", with(2, "19"), true},
+		{"parameters not numbers", with(3, "m=a,t=1,p=1"), true},
+		{"parameters missing", with(3, "m=64"), true},
+		{"zero iterations", with(3, "m=64,t=0,p=1"), true},
+		{"zero parallelism", with(3, "m=64,t=1,p=0"), true},
+		{"parallelism beyond 8 bits", with(3, "m=64,t=1,p=256"), true},
+		{"salt not base64", with(4, "!!!"), true},
+		{"key not base64", with(5, "!!!"), true},
+		{"empty key", with(5, ""), true},
+		{"missing part", strings.Join(parts[:5], "$"), true},
+		{"extra part", hash + "$extra", true},
+		{"bcrypt", "$2a$04$abcdefghijklmnopqrstuu", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := parseArgon2Hash(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (h.memory != 64 || h.iterations != 1 || h.parallelism != 1 ||
+				len(h.salt) != argon2SaltLength || len(h.key) != argon2KeyLength) {
+				t.Errorf("parsed %+v", h)
+			}
+			if tt.wantErr && CheckPassword("correct horse", tt.hash) {
+				t.Error("a malformed hash matches")
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	hashWith := func(p PasswordParams) string {
+		t.Helper()
+		usePasswordParams(t, p)
+		hash, err := HashPassword("correct horse")
+		if err != nil {
+			t.Fatalf("HashPassword: %v", err)
+		}
+		return hash
+	}
+	moreMemory, moreIterations, moreLanes, higherCost := testArgon2Params, testArgon2Params, testArgon2Params, testBcryptParams()
+	moreMemory.Memory = 128
+	moreIterations.Iterations = 2
+	moreLanes.Parallelism = 2
+	higherCost.BcryptCost = bcrypt.MinCost + 1
+
+	current := hashWith(testArgon2Params)
+	otherMemory := hashWith(moreMemory)
+	otherIterations := hashWith(moreIterations)
+	otherLanes := hashWith(moreLanes)
+	bcryptHash := hashWith(testBcryptParams())
+	costlierBcrypt := hashWith(higherCost)
+
+	tests := []struct {
+		name   string
+		params PasswordParams
+		hash   string
+		want   bool
+	}{
+		{"same argon2id parameters", testArgon2Params, current, false},
+		{"memory changed", testArgon2Params, otherMemory, true},
+		{"iterations changed", testArgon2Params, otherIterations, true},
+		{"parallelism changed", testArgon2Params, otherLanes, true},
+		{"bcrypt hash with argon2id configured", testArgon2Params, bcryptHash, true},
+		{"malformed hash", testArgon2Params, "$argon2id$v=19$m=64", true},
+		{"same bcrypt cost", testBcryptParams(), bcryptHash, false},
+		{"bcrypt cost changed", testBcryptParams(), costlierBcrypt, true},
+		{"argon2id hash with bcrypt configured", testBcryptParams(), current, true},
+		{"concurrency does not matter", PasswordParams{Algorithm: "argon2id", Memory: 64, Iterations: 1, Parallelism: 1, Concurrency: 1}, current, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasswordParams(t, tt.params)
+			if got := PasswordNeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("PasswordNeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPasswordParams(t *testing.T) {
+	with := func(change func(p *PasswordParams)) PasswordParams {
+		p := testArgon2Params
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		params  PasswordParams
+		wantErr bool
+	}{
+		{"argon2id", testArgon2Params, false},
+		{"bcrypt", testBcryptParams(), false},
+		{"defaults", DefaultPasswordParams, false},
+		{"unknown algorithm", with(func(p *PasswordParams) { p.Algorithm = "scrypt" }), true},
+		{"zero iterations", with(func(p *PasswordParams) { p.Iterations = 0 }), true},
+		{"zero parallelism", with(func(p *PasswordParams) { p.Parallelism = 0 }), true},
+		{"memory below 8 KiB per lane", with(func(p *PasswordParams) { p.Memory, p.Parallelism = 15, 2 }), true},
+		{"bcrypt cost too low", with(func(p *PasswordParams) { p.Algorithm, p.BcryptCost = "bcrypt", 3 }), true},
+		{"bcrypt cost too high", with(func(p *PasswordParams) { p.Algorithm, p.BcryptCost = "bcrypt", 32 }), true},
+		{"no concurrency", with(func(p *PasswordParams) { p.Concurrency = 0 }), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, previousSlots := passwordParams, hashSlots
+			t.Cleanup(func() { passwordParams, hashSlots = previous, previousSlots })
+
+			err := SetPasswordParams(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && passwordParams != previous {
+				t.Error("rejected settings were applied")
+			}
+		})
+	}
+}
+
+func TestHashSlotsBoundConcurrency(t *testing.T) {
+	p := testArgon2Params
+	p.Concurrency = 1
+	usePasswordParams(t, p)
+
+	// Hold the only slot; a hash must wait until it is freed
+	release := acquireHashSlot()
+	done := make(chan struct{})
+	go func() {
+		HashPassword("correct horse")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("a hash ran while every slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hash did not run after the slot was freed")
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// E.164: a plus sign, a country code not starting with 0, at most 15 digits in all
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizePhone converts a phone number as typed by a user to E.164, e.g.
+// "081-234 5678" with country code "66" becomes "+66812345678". Numbers in
+// international format keep their own country code.
+func NormalizePhone(number, countryCode string) (string, error) {
+	number = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(number))
+
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = "+" + number[2:]
+	case strings.HasPrefix(number, "0") && countryCode != "":
+		// National format; the leading 0 is the trunk prefix
+		number = "+" + countryCode + number[1:]
+	default:
+		return "", errors.New("invalid phone number")
+	}
+
+	if !e164Pattern.MatchString(number) {
+		return "", errors.New("invalid phone number")
+	}
+	return number, nil
+}
+
+// Generate a numeric one-time code of n digits
+func GenerateOTP(n int) (string, error) {
+	return RandomString("0123456789", n)
+}
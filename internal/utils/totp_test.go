@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 secret of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The RFC lists 8 digit codes; 6 digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	const step = int64(1234567890 / totpPeriod)
+	code := totpCode(key, step)
+	start := step * totpPeriod // First second of the code's step
+
+	tests := []struct {
+		name string
+		unix int64
+		ok   bool
+	}{
+		{"first second of its step", start, true},
+		{"last second of its step", start + totpPeriod - 1, true},
+		{"middle of the step before", start - totpPeriod/2, true},
+		{"first second of the step before", start - totpPeriod, true},
+		{"last second two steps early", start - totpPeriod - 1, false},
+		{"last second of the step after", start + 2*totpPeriod - 1, true},
+		{"first second two steps late", start + 2*totpPeriod, false},
+		{"far in the future", start + 100*totpPeriod, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ok := ValidateTOTP(rfcSecret, code, time.Unix(tt.unix, 0), 0)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && matched != step {
+				t.Errorf("matched step %d, want %d", matched, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	const step = int64(1234567890 / totpPeriod)
+	now := time.Unix(step*totpPeriod, 0)
+
+	tests := []struct {
+		name     string
+		codeStep int64
+		lastStep int64
+		ok       bool
+	}{
+		{"never used", step, 0, true},
+		{"previous step used", step, step - 1, true},
+		{"same step used", step, step, false},
+		{"later step used", step, step + 1, false},
+		{"earlier code after a later one", step - 1, step, false},
+		{"next code after the current one", step + 1, step, true},
+		{"drifted code after its step was used", step - 1, step - 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ok := ValidateTOTP(rfcSecret, totpCode(key, tt.codeStep), now, tt.lastStep)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && matched != tt.codeStep {
+				t.Errorf("matched step %d, want %d", matched, tt.codeStep)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsMalformed(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	now := time.Unix(1234567890, 0)
+	code := totpCode(key, now.Unix()/totpPeriod)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		ok     bool
+	}{
+		{"valid", rfcSecret, code, true},
+		{"lower case secret", strings.ToLower(rfcSecret), code, true},
+		{"short code", rfcSecret, code[:5], false},
+		{"long code", rfcSecret, code + "0", false},
+		{"empty code", rfcSecret, "", false},
+		{"wrong code", rfcSecret, "000000", false},
+		{"invalid secret", "not base32!", code, false},
+		{"other secret", "JBSWY3DPEHPK3PXP", code, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now, 0); ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret has %d bytes, want 20", len(key))
+	}
+
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, time.Now(), 0); !ok {
+		t.Error("a fresh secret rejects its own current code")
+	}
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"fiber-api/internal/breach"
 	"fiber-api/internal/config"
 	"fiber-api/internal/database"
 	"fiber-api/internal/handlers"
+	"fiber-api/internal/jobs"
+	"fiber-api/internal/mail"
 	"fiber-api/internal/middleware"
+	"fiber-api/internal/pii"
 	"fiber-api/internal/services"
+	"fiber-api/internal/sms"
+	"fiber-api/internal/utils"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -30,23 +39,181 @@ import (
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
+// @securityDefinitions.apikey UserAPIKey
+// @in header
+// @name X-API-Key
+// @description Personal API key from /me/api-keys, accepted where noted.
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
+	if err := cfg.ValidatePasswordHashing(); err != nil {
+		log.Fatal("Invalid password hashing settings: ", err)
+	}
+	if err := utils.SetPasswordParams(utils.PasswordParams{
+		Algorithm:   cfg.PasswordHashAlgorithm,
+		Memory:      uint32(cfg.Argon2MemoryKiB),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		BcryptCost:  cfg.BcryptCost,
+		Concurrency: cfg.HashConcurrency,
+	}); err != nil {
+		log.Fatal("Invalid password hashing settings: ", err)
+	}
 
 	// Initialize database
-	db := database.NewDatabase(cfg.DatabasePath)
+	cipher := newCipher(cfg)
+	db := database.NewDatabase(cfg.DatabasePath, cipher)
+
+	// "rotate-pii-keys" re-encrypts personal data with the active key and exits
+	if len(os.Args) > 1 && os.Args[1] == "rotate-pii-keys" {
+		count, err := database.SealPII(db.GetDB(), cipher, true)
+		if err != nil {
+			log.Fatal("Failed to rotate encryption keys:", err)
+		}
+		log.Printf("Re-encrypted personal data in %d rows", count)
+		return
+	}
 
 	// Initialize services
-	userService := services.NewUserService(db.GetDB())
-	transferService := services.NewTransferService(db.GetDB())
+	pointLedger := services.NewPointLedger(cfg.PointExpiryDays)
+	walletService := services.NewWalletService(db.GetDB(), pointLedger)
+	campaignService := services.NewCampaignService(db.GetDB(), pointLedger)
+	referralService := services.NewReferralService(db.GetDB(), campaignService)
+	phoneService := services.NewPhoneService(db.GetDB(), sms.NewLogSender(), cfg.DefaultCountryCode, services.PhoneOTPLimits{
+		TTL:            time.Duration(cfg.PhoneOTPTTLMinutes) * time.Minute,
+		MaxAttempts:    cfg.PhoneOTPMaxAttempts,
+		ResendCooldown: time.Duration(cfg.PhoneOTPResendSeconds) * time.Second,
+		MaxPerDay:      cfg.PhoneOTPMaxPerDay,
+	})
+	passwordPolicy := services.NewPasswordPolicy(services.PasswordRules{
+		MinLength:          cfg.PasswordMinLength,
+		MaxLength:          cfg.PasswordMaxLength,
+		RequireLower:       cfg.PasswordRequireLower,
+		RequireUpper:       cfg.PasswordRequireUpper,
+		RequireDigit:       cfg.PasswordRequireDigit,
+		RequireSymbol:      cfg.PasswordRequireSymbol,
+		RejectPersonalInfo: cfg.PasswordRejectPersonalInfo,
+	}, newBreachChecker(cfg))
+	userService := services.NewUserService(db.GetDB(), campaignService, referralService, phoneService, passwordPolicy)
+	notificationService := services.NewNotificationService(db.GetDB())
+	paymentRequestService := services.NewPaymentRequestService(db.GetDB(), notificationService, time.Duration(cfg.PaymentReminderHours)*time.Hour)
+	loginGuard := services.NewLoginGuard(db.GetDB(), services.LoginLimits{
+		DelayAfter:         cfg.LoginDelayAfter,
+		MaxAccountFailures: cfg.LoginMaxFailures,
+		MaxIPFailures:      cfg.LoginIPMaxFailures,
+		Lockout:            time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
+	})
+	mfaService := services.NewMFAService(db.GetDB(), loginGuard, cfg.MFAIssuer, cfg.MFAMaxAttempts, time.Duration(cfg.MFALockoutMinutes)*time.Minute)
+	pinService := services.NewPINService(db.GetDB(), loginGuard, cfg.TransferPINThreshold, cfg.PINMaxAttempts, time.Duration(cfg.PINLockoutMinutes)*time.Minute)
+	sessionService := services.NewSessionService(db.GetDB(), loginGuard)
+	apiKeyService := services.NewAPIKeyService(db.GetDB(), mfaService, loginGuard)
+	mailer := newMailer(cfg)
+	passwordResetService := services.NewPasswordResetService(db.GetDB(), mailer, loginGuard, passwordPolicy, cfg.PasswordResetURL, time.Duration(cfg.PasswordResetTTLMinutes)*time.Minute)
+	emailVerificationService := services.NewEmailVerificationService(db.GetDB(), mailer, cfg.EmailVerificationURL, services.EmailVerificationLimits{
+		TTL:            time.Duration(cfg.EmailVerificationTTLHours) * time.Hour,
+		ResendCooldown: time.Duration(cfg.EmailVerificationResendSeconds) * time.Second,
+		MaxPerDay:      cfg.EmailVerificationMaxPerDay,
+	})
+	profileService := services.NewProfileService(db.GetDB(), phoneService, loginGuard, passwordPolicy)
+	accountService := services.NewAccountService(db.GetDB(), pointLedger, mfaService, loginGuard)
+	transferService := services.NewTransferService(db.GetDB(), pointLedger, campaignService, referralService, paymentRequestService, pinService, phoneService)
+	pointExpiryService := services.NewPointExpiryService(db.GetDB(), pointLedger)
+	catalogService := services.NewCatalogService(db.GetDB(), pointLedger)
+	merchantService := services.NewMerchantService(db.GetDB(), pointLedger, pinService, time.Duration(cfg.PaymentIntentTTLMinutes)*time.Minute)
+	exchangeService := services.NewExchangeService(db.GetDB(), pointLedger, time.Duration(cfg.ConversionQuoteTTLSeconds)*time.Second)
+	escrowService := services.NewEscrowService(db.GetDB(), pointLedger, pinService, time.Duration(cfg.EscrowTimeoutHours)*time.Hour, cfg.EscrowMaxHours)
+	voucherService := services.NewVoucherService(db.GetDB(), pointLedger, campaignService, pinService, time.Duration(cfg.VoucherValidityDays)*24*time.Hour, cfg.VoucherMaxDays)
+	qrService := services.NewQRService(db.GetDB(), cfg.QRSecret, time.Duration(cfg.QRTTLMinutes)*time.Minute)
+
+	// Seed the welcome campaign and promote configured operators
+	if err := campaignService.Seed(cfg.SignupBonus, cfg.CampaignInitialFunds); err != nil {
+		log.Fatal("Failed to seed campaigns:", err)
+	}
+	if err := userService.PromoteAdmins(cfg.AdminEmails); err != nil {
+		log.Fatal("Failed to promote admins:", err)
+	}
+	if err := referralService.BackfillCodes(); err != nil {
+		log.Fatal("Failed to backfill referral codes:", err)
+	}
+	if err := phoneService.NormalizeExisting(); err != nil {
+		log.Fatal("Failed to normalize phone numbers:", err)
+	}
+
+	// Move balances that predate wallets into the default currency, then back them with lots
+	if err := walletService.Seed(); err != nil {
+		log.Fatal("Failed to seed currencies:", err)
+	}
+	if err := walletService.BackfillWallets(); err != nil {
+		log.Fatal("Failed to backfill wallets:", err)
+	}
+	if err := pointExpiryService.BackfillLots(); err != nil {
+		log.Fatal("Failed to backfill point lots:", err)
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret)
-	userHandler := handlers.NewUserHandler(userService)
+	authHandler := handlers.NewAuthHandler(userService, mfaService, loginGuard, emailVerificationService, sessionService, cfg.JWTSecret, time.Duration(cfg.MFAChallengeTTLMinutes)*time.Minute)
+	passwordHandler := handlers.NewPasswordHandler(passwordResetService)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	securityHandler := handlers.NewSecurityHandler(loginGuard, userService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	userHandler := handlers.NewUserHandler(userService, walletService)
+	pinHandler := handlers.NewPINHandler(pinService)
+	phoneHandler := handlers.NewPhoneHandler(phoneService)
+	profileHandler := handlers.NewProfileHandler(profileService, sessionService, cfg.JWTSecret)
+	accountHandler := handlers.NewAccountHandler(accountService)
 	transferHandler := handlers.NewTransferHandler(transferService)
 	healthHandler := handlers.NewHealthHandler()
+	pointHandler := handlers.NewPointHandler(pointExpiryService, cfg.ExpiringSoonDays)
+	campaignHandler := handlers.NewCampaignHandler(campaignService)
+	referralHandler := handlers.NewReferralHandler(referralService)
+	catalogHandler := handlers.NewCatalogHandler(catalogService)
+	merchantHandler := handlers.NewMerchantHandler(merchantService)
+	qrHandler := handlers.NewQRHandler(qrService)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	exchangeHandler := handlers.NewExchangeHandler(exchangeService)
+	escrowHandler := handlers.NewEscrowHandler(escrowService)
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(paymentRequestService, transferService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	voucherHandler := handlers.NewVoucherHandler(voucherService)
+
+	// Schedule background jobs
+	scheduler := jobs.NewScheduler()
+	scheduler.Daily("expire-points", cfg.ExpiryJobHour, func(now time.Time) error {
+		expired, err := pointExpiryService.ExpireLots(now)
+		log.Printf("Expired %d point lots", expired)
+		return err
+	})
+	scheduler.Daily("birthday-bonus", cfg.BirthdayJobHour, func(now time.Time) error {
+		rewarded, err := campaignService.RewardBirthdays(now)
+		log.Printf("Paid birthday bonuses to %d users", rewarded)
+		return err
+	})
+	scheduler.Every("refund-escrows", time.Duration(cfg.EscrowJobIntervalMins)*time.Minute, func(now time.Time) error {
+		refunded, err := escrowService.RefundExpired(now)
+		if refunded > 0 {
+			log.Printf("Refunded %d expired escrows", refunded)
+		}
+		return err
+	})
+	scheduler.Daily("expire-vouchers", cfg.ExpiryJobHour, func(now time.Time) error {
+		expired, err := voucherService.ExpireVouchers(now)
+		log.Printf("Refunded %d expired vouchers", expired)
+		return err
+	})
+	scheduler.Daily("payment-request-reminders", cfg.ReminderJobHour, func(now time.Time) error {
+		reminded, err := paymentRequestService.RemindOverdue(now)
+		log.Printf("Reminded %d unpaid payment request shares", reminded)
+		return err
+	})
+	scheduler.Daily("purge-sessions", cfg.ExpiryJobHour, func(now time.Time) error {
+		purged, err := sessionService.PurgeExpired(now)
+		log.Printf("Purged %d expired sessions", purged)
+		return err
+	})
+	scheduler.Start()
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -57,7 +224,15 @@ func main() {
 	app.Use(cors.New())
 
 	// Initialize JWT middleware
-	jwtMiddleware := middleware.JWTMiddleware(cfg.JWTSecret)
+	jwtMiddleware := middleware.JWTMiddleware(cfg.JWTSecret, userService, sessionService)
+	adminMiddleware := middleware.AdminMiddleware()
+	merchantMiddleware := middleware.MerchantMiddleware(merchantService)
+	verifiedMiddleware := middleware.VerifiedEmailMiddleware(userService, cfg.RequireVerifiedEmail)
+
+	// Routes that integrations may also call with a personal API key
+	balanceAuth := middleware.APIKeyOrJWTMiddleware(jwtMiddleware, apiKeyService, services.ScopeReadBalance)
+	historyAuth := middleware.APIKeyOrJWTMiddleware(jwtMiddleware, apiKeyService, services.ScopeReadHistory)
+	transferAuth := middleware.APIKeyOrJWTMiddleware(jwtMiddleware, apiKeyService, services.ScopeTransfer)
 
 	// Swagger documentation
 	app.Get("/swagger/*", fiberSwagger.WrapHandler)
@@ -69,15 +244,150 @@ func main() {
 	// Authentication routes
 	app.Post("/register", authHandler.Register)
 	app.Post("/login", authHandler.Login)
+	app.Post("/login/mfa", authHandler.LoginMFA)
+	app.Post("/password/forgot", passwordHandler.ForgotPassword)
+	app.Post("/password/reset", passwordHandler.ResetPassword)
+	app.Post("/email/verify", emailVerificationHandler.VerifyEmail)
 
 	// Protected routes
 	app.Get("/me", jwtMiddleware, userHandler.GetMe)
-	app.Get("/points/balance", jwtMiddleware, userHandler.GetPointBalance)
+	app.Patch("/me", jwtMiddleware, profileHandler.UpdateProfile)
+	app.Post("/me/password", jwtMiddleware, profileHandler.ChangePassword)
+	app.Get("/me/export", jwtMiddleware, accountHandler.ExportAccount)
+	app.Post("/me/close", jwtMiddleware, accountHandler.CloseAccount)
+	app.Get("/me/sessions", jwtMiddleware, sessionHandler.GetSessions)
+	app.Delete("/me/sessions/:id", jwtMiddleware, sessionHandler.RevokeSession)
+	app.Get("/me/api-keys", jwtMiddleware, apiKeyHandler.GetAPIKeys)
+	app.Post("/me/api-keys", jwtMiddleware, apiKeyHandler.CreateAPIKey)
+	app.Delete("/me/api-keys/:id", jwtMiddleware, apiKeyHandler.RevokeAPIKey)
+	app.Post("/me/email/verification", jwtMiddleware, emailVerificationHandler.ResendVerification)
+	app.Put("/me/phone", jwtMiddleware, phoneHandler.ChangePhone)
+	app.Post("/me/phone/code", jwtMiddleware, phoneHandler.SendCode)
+	app.Post("/me/phone/verify", jwtMiddleware, phoneHandler.VerifyPhone)
+	app.Get("/me/pin", jwtMiddleware, pinHandler.GetPINStatus)
+	app.Post("/me/pin", jwtMiddleware, pinHandler.SetPIN)
+	app.Put("/me/pin", jwtMiddleware, pinHandler.ChangePIN)
+	app.Post("/me/pin/reset", jwtMiddleware, pinHandler.ResetPIN)
+	app.Post("/me/mfa/enroll", jwtMiddleware, mfaHandler.Enroll)
+	app.Post("/me/mfa/confirm", jwtMiddleware, mfaHandler.Confirm)
+	app.Post("/me/mfa/recovery-codes", jwtMiddleware, mfaHandler.RegenerateRecoveryCodes)
+	app.Post("/me/mfa/disable", jwtMiddleware, mfaHandler.Disable)
+	app.Get("/points/balance", balanceAuth, userHandler.GetPointBalance)
+	app.Get("/wallets", balanceAuth, walletHandler.GetWallets)
+	app.Get("/currencies", jwtMiddleware, walletHandler.GetCurrencies)
+	app.Get("/exchange-rates", jwtMiddleware, exchangeHandler.GetRates)
+	app.Post("/conversions/quote", jwtMiddleware, exchangeHandler.Quote)
+	app.Post("/conversions/:id/execute", jwtMiddleware, exchangeHandler.Execute)
+	app.Post("/escrows", jwtMiddleware, verifiedMiddleware, escrowHandler.CreateEscrow)
+	app.Get("/escrows", jwtMiddleware, escrowHandler.GetEscrows)
+	app.Post("/escrows/:id/release", jwtMiddleware, escrowHandler.ReleaseEscrow)
+	app.Post("/escrows/:id/dispute", jwtMiddleware, escrowHandler.DisputeEscrow)
+	app.Post("/payment-requests", jwtMiddleware, paymentRequestHandler.CreatePaymentRequest)
+	app.Get("/payment-requests", jwtMiddleware, paymentRequestHandler.GetPaymentRequests)
+	app.Get("/payment-requests/:id", jwtMiddleware, paymentRequestHandler.GetPaymentRequest)
+	app.Post("/payment-requests/:id/pay", jwtMiddleware, verifiedMiddleware, paymentRequestHandler.PayShare)
+	app.Post("/payment-requests/:id/remind", jwtMiddleware, paymentRequestHandler.RemindParticipants)
+	app.Post("/payment-requests/:id/cancel", jwtMiddleware, paymentRequestHandler.CancelPaymentRequest)
+	app.Post("/vouchers", jwtMiddleware, verifiedMiddleware, voucherHandler.CreateVoucher)
+	app.Get("/vouchers", jwtMiddleware, voucherHandler.GetVouchers)
+	app.Post("/vouchers/redeem", jwtMiddleware, voucherHandler.RedeemVoucher)
+	app.Get("/notifications", jwtMiddleware, notificationHandler.GetNotifications)
+	app.Post("/notifications/read", jwtMiddleware, notificationHandler.MarkNotificationsRead)
 	app.Get("/users/search", jwtMiddleware, userHandler.SearchUserByLBK)
-	app.Post("/points/transfer", jwtMiddleware, transferHandler.TransferPoints)
-	app.Get("/points/history", jwtMiddleware, transferHandler.GetTransferHistory)
+	app.Post("/points/transfer", transferAuth, verifiedMiddleware, transferHandler.TransferPoints)
+	app.Get("/points/history", historyAuth, transferHandler.GetTransferHistory)
+	app.Get("/points/expiring", jwtMiddleware, pointHandler.GetExpiringPoints)
+	app.Get("/referrals", jwtMiddleware, referralHandler.GetReferrals)
+	app.Get("/catalog", jwtMiddleware, catalogHandler.GetCatalog)
+	app.Post("/catalog/:id/redeem", jwtMiddleware, catalogHandler.Redeem)
+	app.Get("/orders", jwtMiddleware, catalogHandler.GetOrders)
+	app.Post("/orders/:id/cancel", jwtMiddleware, catalogHandler.CancelOrder)
+	app.Get("/payments/:id", jwtMiddleware, merchantHandler.GetPayment)
+	app.Post("/payments/:id/confirm", jwtMiddleware, verifiedMiddleware, merchantHandler.ConfirmPayment)
+	app.Post("/qr", jwtMiddleware, qrHandler.GenerateQR)
+	app.Post("/qr/parse", jwtMiddleware, qrHandler.ParseQR)
+
+	// Merchant routes, authenticated with merchant API keys
+	merchant := app.Group("/merchant", merchantMiddleware)
+	merchant.Post("/payment-intents", merchantHandler.CreatePaymentIntent)
+	merchant.Get("/payment-intents/:id", merchantHandler.GetMerchantIntent)
+	merchant.Post("/payment-intents/:id/cancel", merchantHandler.CancelPaymentIntent)
+	merchant.Get("/transactions", merchantHandler.GetTransactions)
+
+	// Admin routes
+	admin := app.Group("/admin", jwtMiddleware, adminMiddleware)
+	admin.Post("/campaigns", campaignHandler.CreateCampaign)
+	admin.Get("/campaigns", campaignHandler.ListCampaigns)
+	admin.Patch("/campaigns/:id", campaignHandler.UpdateCampaign)
+	admin.Get("/campaign-account", campaignHandler.GetAccount)
+	admin.Post("/campaign-account/fund", campaignHandler.FundAccount)
+	admin.Post("/catalog", catalogHandler.CreateItem)
+	admin.Get("/catalog", catalogHandler.ListItems)
+	admin.Patch("/catalog/:id", catalogHandler.UpdateItem)
+	admin.Get("/orders", catalogHandler.ListAllOrders)
+	admin.Patch("/orders/:id", catalogHandler.UpdateOrderStatus)
+	admin.Post("/merchants", merchantHandler.CreateMerchant)
+	admin.Get("/merchants", merchantHandler.ListMerchants)
+	admin.Post("/merchants/:id/api-keys", merchantHandler.CreateAPIKey)
+	admin.Delete("/merchants/:id/api-keys/:keyId", merchantHandler.RevokeAPIKey)
+	admin.Get("/currencies", walletHandler.ListCurrencies)
+	admin.Post("/currencies", walletHandler.CreateCurrency)
+	admin.Patch("/currencies/:code", walletHandler.UpdateCurrency)
+	admin.Post("/currencies/:code/issue", walletHandler.IssuePoints)
+	admin.Get("/exchange-rates", exchangeHandler.ListRates)
+	admin.Post("/exchange-rates", exchangeHandler.CreateRate)
+	admin.Get("/escrows", escrowHandler.ListAllEscrows)
+	admin.Post("/escrows/:id/resolve", escrowHandler.ResolveEscrow)
+	admin.Get("/vouchers", voucherHandler.ListAllVouchers)
+	admin.Post("/vouchers", voucherHandler.IssueVouchers)
+	admin.Post("/users/:id/mfa/reset", mfaHandler.ResetMFA)
+	admin.Post("/users/:id/unlock", securityHandler.UnlockUser)
+	admin.Get("/users/:id/profile-changes", profileHandler.ListProfileChanges)
+	admin.Get("/security-events", securityHandler.ListSecurityEvents)
 
 	// Start server
 	log.Printf("Server starting on port %s...", cfg.ServerPort)
 	log.Fatal(app.Listen(cfg.ServerPort))
 }
+
+// newMailer picks the mail transport configured by MAIL_DRIVER
+func newMailer(cfg *config.Config) mail.Mailer {
+	if cfg.MailDriver == "smtp" {
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return mail.NewLogMailer(cfg.MailLogFile, cfg.MailFrom)
+}
+
+// newBreachChecker opens the breached-password corpus, if one is configured
+func newBreachChecker(cfg *config.Config) breach.Checker {
+	if cfg.BreachedPasswordsDir == "" {
+		return nil
+	}
+	store, err := breach.NewRangeStore(cfg.BreachedPasswordsDir, cfg.BreachedPasswordMinCount)
+	if err != nil {
+		log.Fatal("Failed to open breached passwords:", err)
+	}
+	return store
+}
+
+func newCipher(cfg *config.Config) *pii.Cipher {
+	var keys pii.KeyProvider
+	var err error
+	switch cfg.PIIKeyProvider {
+	case "env":
+		keys, err = pii.NewEnvKeyProvider(cfg.PIIKeys, cfg.PIIActiveKey, cfg.PIIIndexKey)
+	case "file":
+		keys, err = pii.NewFileKeyProvider(cfg.PIIKeyFile)
+	case "dev":
+		// Only ever chosen explicitly, so a missing key cannot quietly leave
+		// production data readable with the public keys
+		log.Println("WARNING: PII_KEY_PROVIDER=dev, personal data is encrypted with the public development keys")
+		keys = pii.NewDevelopmentKeyProvider()
+	default:
+		err = fmt.Errorf("unknown provider %q", cfg.PIIKeyProvider)
+	}
+	if err != nil {
+		log.Fatal("Failed to load encryption keys (set PII_KEYS, or PII_KEY_PROVIDER=dev for development):", err)
+	}
+	return pii.NewCipher(keys)
+}